@@ -0,0 +1,135 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+const Prefix = "/api/v1"
+
+type Operation struct {
+	Id     string
+	Method string
+	Path   string
+}
+
+type document struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type operationObject struct {
+	OperationId string `json:"operationId"`
+}
+
+var httpMethods = map[string]bool{
+	"get":    true,
+	"put":    true,
+	"post":   true,
+	"delete": true,
+	"patch":  true,
+}
+
+// Operations returns every operation declared in the spec, sorted by path and method.
+func Operations() ([]Operation, error) {
+	var doc document
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi spec, %w", err)
+	}
+	ops := []Operation{}
+	for p, item := range doc.Paths {
+		for method, raw := range item {
+			if !httpMethods[method] {
+				continue
+			}
+			var op operationObject
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("failed to parse operation %s %s, %w", method, p, err)
+			}
+			if op.OperationId == "" {
+				return nil, fmt.Errorf("operation %s %s has no operationId", method, p)
+			}
+			ops = append(ops, Operation{
+				Id:     op.OperationId,
+				Method: strings.ToUpper(method),
+				Path:   p,
+			})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path == ops[j].Path {
+			return ops[i].Method < ops[j].Method
+		}
+		return ops[i].Path < ops[j].Path
+	})
+	return ops, nil
+}
+
+// OperationById looks up a single operation of the spec.
+func OperationById(id string) (*Operation, error) {
+	ops, err := Operations()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if op.Id == id {
+			return &op, nil
+		}
+	}
+	return nil, fmt.Errorf("operation %s not found in openapi spec", id)
+}
+
+// EchoPath converts an openapi path template (/rollup/{id}) to the echo form (/rollup/:id).
+func EchoPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			parts[i] = ":" + strings.TrimSuffix(strings.TrimPrefix(part, "{"), "}")
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// CheckRoutes compares the routes registered under Prefix with the spec and
+// fails on any operation that is missing on either side.
+func CheckRoutes(routes []*echo.Route) error {
+	ops, err := Operations()
+	if err != nil {
+		return err
+	}
+	declared := map[string]bool{}
+	for _, op := range ops {
+		declared[op.Method+" "+EchoPath(op.Path)] = true
+	}
+	registered := map[string]bool{}
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, Prefix) {
+			continue
+		}
+		registered[r.Method+" "+r.Path] = true
+	}
+
+	var drift []string
+	for key := range registered {
+		if !declared[key] {
+			drift = append(drift, "undocumented route "+key)
+		}
+	}
+	for key := range declared {
+		if !registered[key] {
+			drift = append(drift, "unimplemented operation "+key)
+		}
+	}
+	if len(drift) > 0 {
+		sort.Strings(drift)
+		return fmt.Errorf("api routes drift from openapi spec: %s", strings.Join(drift, ", "))
+	}
+	return nil
+}
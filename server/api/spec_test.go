@@ -0,0 +1,352 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g1g2-lab/automation/api"
+	"github.com/g1g2-lab/automation/l2"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/pkg/http"
+	"github.com/g1g2-lab/automation/server"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+)
+
+// spec checks json values against the schemas of openapi.json. It knows the
+// keywords the spec uses and, unlike openapi, rejects properties an object
+// schema does not declare, so undocumented response fields fail too.
+type spec struct {
+	doc map[string]interface{}
+}
+
+func loadSpec(t *testing.T) *spec {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(api.Spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &spec{doc: doc}
+}
+
+// resolve follows the $ref of node within the spec.
+func (s *spec) resolve(node map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var cur interface{} = s.doc
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			cur = cur.(map[string]interface{})[part]
+		}
+		node = cur.(map[string]interface{})
+	}
+}
+
+// flatten merges the allOf branches of schema into one schema.
+func (s *spec) flatten(schema map[string]interface{}) map[string]interface{} {
+	schema = s.resolve(schema)
+	branches, ok := schema["allOf"].([]interface{})
+	if !ok {
+		return schema
+	}
+	merged := map[string]interface{}{}
+	properties := map[string]interface{}{}
+	required := []interface{}{}
+	for _, branch := range branches {
+		for key, value := range s.flatten(branch.(map[string]interface{})) {
+			switch key {
+			case "properties":
+				for name, property := range value.(map[string]interface{}) {
+					properties[name] = property
+				}
+			case "required":
+				required = append(required, value.([]interface{})...)
+			default:
+				merged[key] = value
+			}
+		}
+	}
+	merged["properties"] = properties
+	merged["required"] = required
+	return merged
+}
+
+func (s *spec) validate(schema map[string]interface{}, v interface{}, at string) []string {
+	schema = s.flatten(schema)
+	if v == nil {
+		if schema["nullable"] == true || len(schema) == 0 {
+			return nil
+		}
+		return []string{at + " is null"}
+	}
+	errs := []string{}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || fmt.Sprint(e) == fmt.Sprint(v)
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s is %v, not one of %v", at, v, enum))
+		}
+	}
+	kind, _ := schema["type"].(string)
+	if kind == "" && schema["properties"] != nil {
+		kind = "object"
+	}
+	switch kind {
+	case "object":
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s is %T, not an object", at, v))
+		}
+		for _, name := range requiredOf(schema) {
+			if _, ok := object[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s misses required %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, value := range object {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				// objects without properties are free form
+				if len(properties) > 0 {
+					errs = append(errs, fmt.Sprintf("%s.%s is not in the spec", at, name))
+				}
+				continue
+			}
+			errs = append(errs, s.validate(property, value, at+"."+name)...)
+		}
+	case "array":
+		array, ok := v.([]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s is %T, not an array", at, v))
+		}
+		if min, ok := schema["minItems"].(float64); ok && float64(len(array)) < min {
+			errs = append(errs, fmt.Sprintf("%s has %d items, less than %v", at, len(array), min))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range array {
+				errs = append(errs, s.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%s is %T, not a string", at, v))
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok || (kind == "integer" && n != math.Trunc(n)) {
+			return append(errs, fmt.Sprintf("%s is %v, not an %s", at, v, kind))
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			errs = append(errs, fmt.Sprintf("%s is %v, less than %v", at, n, min))
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			errs = append(errs, fmt.Sprintf("%s is %v, more than %v", at, n, max))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%s is %T, not a boolean", at, v))
+		}
+	}
+	return errs
+}
+
+func requiredOf(schema map[string]interface{}) []interface{} {
+	required, _ := schema["required"].([]interface{})
+	return required
+}
+
+// response returns the schema of the response of operation id for status,
+// or false when the spec does not declare the status.
+func (s *spec) response(op api.Operation, status int) (map[string]interface{}, bool) {
+	item := s.doc["paths"].(map[string]interface{})[op.Path].(map[string]interface{})
+	operation := item[strings.ToLower(op.Method)].(map[string]interface{})
+	response, ok := operation["responses"].(map[string]interface{})[fmt.Sprint(status)].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	response = s.resolve(response)
+	content, ok := response["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}, true
+	}
+	return content["schema"].(map[string]interface{}), true
+}
+
+type specCase struct {
+	op     string
+	params map[string]string
+	query  string
+	body   string
+}
+
+// setupServer registers the handlers of the rollup server in a work dir with
+// a rollup that waits to come online.
+func setupServer(t *testing.T) *echo.Echo {
+	config, err := l2.NewL2ConfigFromFile("../rollup_server_prod.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := filepath.Join(t.TempDir(), "server")
+	if err := os.MkdirAll(filepath.Join(work, "build", "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	rollupDb := db.NewLocalDatabase(context.Background(), "build/db")
+	err = rollupDb.CreateRollup(&types.Rollup{
+		Name:            "alpha",
+		ChainId:         167001,
+		RpcUrl:          "http://127.0.0.1:1",
+		SequencerRpcUrl: "http://127.0.0.1:1",
+		L1Rollup:        "0x0000000000000000000000000000000000000001",
+		ExecutionImage:  "g1g2/l2_geth:v1",
+		ExecutionDigest: "sha256:1111",
+		ConsensusImage:  "g1g2/consensus:v1",
+		ConsensusDigest: "sha256:2222",
+		CreatedAt:       time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		L1:              types.L1Net{Name: "l1_dev", ChainId: 1337},
+		Step:            types.WaitItOnline,
+		Status:          "verifying",
+		BasePort:        10545,
+		Node:            types.DefaultNodeProfile,
+		Consensus:       types.ConsensusSpec{Prover: types.ProverSpec{Mode: types.ProverDummy}},
+		NativeCurrency:  types.NativeCurrency{Name: "Ether", Symbol: "ETH", Decimals: 18},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Validator = &http.CustomValidator{Validator: validator.New()}
+	server.NewRollupHandler(rollupDb, config).SetupRollupRouter(e, rollupDb)
+	if err := api.CheckRoutes(e.Routes()); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	s := loadSpec(t)
+	e := setupServer(t)
+	alpha := map[string]string{"id": "alpha"}
+	missing := map[string]string{"id": "missing"}
+	cases := []specCase{
+		{op: "getOpenAPI"},
+		{op: "listRollups"},
+		{op: "getRollup", params: alpha},
+		{op: "getRollup", params: missing},
+		{op: "createRollup", params: map[string]string{"id": "beta"}, body: `{`},
+		{op: "createRollup", params: map[string]string{"id": "beta"}, body: `{}`},
+		{op: "createRollup", params: map[string]string{"id": "beta"}, query: "dry_run=true", body: `{"name": "beta", "chain_id": 167002}`},
+		{op: "createRollup", params: alpha, body: `{"name": "alpha", "chain_id": 167001}`},
+		{op: "deleteRollup", params: alpha},
+		{op: "getRollupStatus", params: alpha},
+		{op: "getRollupStatus", params: missing},
+		{op: "getRollupLogs", params: alpha, query: "tail=-1"},
+		{op: "getRollupLogs", params: alpha, query: "service=unknown"},
+		{op: "getProverStatus", params: alpha},
+		{op: "scaleReplicas", params: alpha, body: `{"replicas": -1}`},
+		{op: "rotateJwtSecret", params: missing},
+		{op: "upgradeContracts", params: alpha, body: `{}`},
+		{op: "upgradeContracts", params: missing, body: `{"version": 2}`},
+		{op: "upgradeImage", params: alpha, body: `{"image": "prover", "version": "v2"}`},
+		{op: "upgradeImage", params: missing, body: `{"image": "execution", "version": "v2"}`},
+		{op: "stopRollup", params: alpha},
+		{op: "startRollup", params: alpha},
+		{op: "archiveRollup", params: alpha},
+		{op: "backupRollup", params: alpha},
+		{op: "listBackups", params: alpha},
+		{op: "listBackups", params: missing},
+		{op: "restoreRollup", body: `{}`},
+		{op: "restoreRollup", body: `{"file": "missing.tar.gz"}`},
+		{op: "forkRollup", params: alpha, body: `{}`},
+		{op: "forkRollup", params: missing, body: `{"name": "beta", "chain_id": 167002}`},
+		{op: "verifyRollup", params: missing},
+		{op: "requestFaucet", params: alpha, body: `{}`},
+		{op: "requestFaucet", params: alpha, body: `{"address": "0x0000000000000000000000000000000000000002"}`},
+		{op: "fundWallets", params: alpha, body: `{"wallets": []}`},
+		{op: "fundWallets", params: missing, body: `{"wallets": [{"address": "0x0000000000000000000000000000000000000002"}]}`},
+		{op: "listWallets", params: alpha},
+		{op: "listWallets", params: missing},
+		{op: "getChainMetadata", params: alpha},
+		{op: "getChainMetadata", params: missing},
+		{op: "listContracts", params: missing},
+	}
+
+	ops, err := api.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byId := map[string]api.Operation{}
+	for _, op := range ops {
+		byId[op.Id] = op
+	}
+	covered := map[string]bool{}
+	for _, c := range cases {
+		op, ok := byId[c.op]
+		if !ok {
+			t.Fatalf("operation %s is not in the spec", c.op)
+		}
+		covered[c.op] = true
+		target := op.Path
+		for name, value := range c.params {
+			target = strings.ReplaceAll(target, "{"+name+"}", value)
+		}
+		if c.query != "" {
+			target += "?" + c.query
+		}
+		method := op.Method
+		req := httptest.NewRequest(method, target, strings.NewReader(c.body))
+		if c.body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		name := fmt.Sprintf("%s %s", method, target)
+		schema, ok := s.response(op, rec.Code)
+		if !ok {
+			t.Errorf("%s: status %d is not in the spec, body %s", name, rec.Code, rec.Body)
+			continue
+		}
+		var body interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: response is not json, %v", name, err)
+			continue
+		}
+		for _, err := range s.validate(schema, body, "response") {
+			t.Errorf("%s: %d %s", name, rec.Code, err)
+		}
+	}
+
+	missed := []string{}
+	for _, op := range ops {
+		if !covered[op.Id] {
+			missed = append(missed, op.Id)
+		}
+	}
+	sort.Strings(missed)
+	if len(missed) > 0 {
+		t.Fatalf("operations without a response check: %s", strings.Join(missed, ", "))
+	}
+}
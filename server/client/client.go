@@ -0,0 +1,277 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g1g2-lab/automation/api"
+	"github.com/g1g2-lab/automation/types"
+)
+
+// Client talks to the rollup API. Every call is resolved through the
+// operationId of the embedded openapi spec, so the client cannot drift from
+// the document the server is checked against.
+type Client struct {
+	baseUrl string
+	http    *http.Client
+}
+
+// Error is returned when the server answers with a failed envelope.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rollup api: %d %s", e.StatusCode, e.Message)
+}
+
+type envelope struct {
+	Message string          `json:"msg"`
+	Code    string          `json:"code"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func New(baseUrl string) *Client {
+	return &Client{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		http:    &http.Client{Timeout: time.Minute * 30},
+	}
+}
+
+func (c *Client) GetRollup(ctx context.Context, name string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	err := c.do(ctx, "getRollup", map[string]string{"id": name}, nil, nil, rollup)
+	return rollup, err
+}
+
+func (c *Client) ListRollups(ctx context.Context) ([]*types.Rollup, error) {
+	rollups := []*types.Rollup{}
+	err := c.do(ctx, "listRollups", nil, nil, nil, &rollups)
+	return rollups, err
+}
+
+func (c *Client) CreateRollup(ctx context.Context, req *types.CreateRollupRequest) (*types.RollupJob, error) {
+	job := &types.RollupJob{}
+	err := c.do(ctx, "createRollup", map[string]string{"id": req.Name}, nil, req, job)
+	return job, err
+}
+
+// ForkRollup forks the rollup source into a new one, which is provisioned in
+// the background like a created rollup.
+func (c *Client) ForkRollup(ctx context.Context, source string, req *types.ForkRollupRequest) (*types.RollupJob, error) {
+	job := &types.RollupJob{}
+	err := c.do(ctx, "forkRollup", map[string]string{"id": source}, nil, req, job)
+	return job, err
+}
+
+// PlanRollup renders the artifacts of the request on the server without
+// deploying anything.
+func (c *Client) PlanRollup(ctx context.Context, req *types.CreateRollupRequest) (*types.RollupPlan, error) {
+	plan := &types.RollupPlan{}
+	query := url.Values{}
+	query.Set("dry_run", "true")
+	err := c.do(ctx, "createRollup", map[string]string{"id": req.Name}, query, req, plan)
+	return plan, err
+}
+
+func (c *Client) GetRollupStatus(ctx context.Context, name string) (*types.RollupJob, error) {
+	job := &types.RollupJob{}
+	err := c.do(ctx, "getRollupStatus", map[string]string{"id": name}, nil, nil, job)
+	return job, err
+}
+
+func (c *Client) GetRollupLogs(ctx context.Context, name, service string, tail int) (string, error) {
+	query := url.Values{}
+	query.Set("tail", strconv.Itoa(tail))
+	if service != "" {
+		query.Set("service", service)
+	}
+	var logs string
+	err := c.do(ctx, "getRollupLogs", map[string]string{"id": name}, query, nil, &logs)
+	return logs, err
+}
+
+func (c *Client) GetProverStatus(ctx context.Context, name string) (*types.ProverStatus, error) {
+	status := &types.ProverStatus{}
+	err := c.do(ctx, "getProverStatus", map[string]string{"id": name}, nil, nil, status)
+	return status, err
+}
+
+func (c *Client) ScaleReplicas(ctx context.Context, name string, replicas int) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	req := &types.ScaleReplicasRequest{Replicas: replicas}
+	err := c.do(ctx, "scaleReplicas", map[string]string{"id": name}, nil, req, rollup)
+	return rollup, err
+}
+
+func (c *Client) RotateJwtSecret(ctx context.Context, name string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	err := c.do(ctx, "rotateJwtSecret", map[string]string{"id": name}, nil, nil, rollup)
+	return rollup, err
+}
+
+func (c *Client) UpgradeContracts(ctx context.Context, name string, version int) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	req := &types.UpgradeContractsRequest{Version: version}
+	err := c.do(ctx, "upgradeContracts", map[string]string{"id": name}, nil, req, rollup)
+	return rollup, err
+}
+
+func (c *Client) UpgradeImage(ctx context.Context, name, image, version string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	req := &types.UpgradeImageRequest{Image: image, Version: version}
+	err := c.do(ctx, "upgradeImage", map[string]string{"id": name}, nil, req, rollup)
+	return rollup, err
+}
+
+func (c *Client) StopRollup(ctx context.Context, name string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	err := c.do(ctx, "stopRollup", map[string]string{"id": name}, nil, nil, rollup)
+	return rollup, err
+}
+
+func (c *Client) StartRollup(ctx context.Context, name string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	err := c.do(ctx, "startRollup", map[string]string{"id": name}, nil, nil, rollup)
+	return rollup, err
+}
+
+func (c *Client) ArchiveRollup(ctx context.Context, name string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	err := c.do(ctx, "archiveRollup", map[string]string{"id": name}, nil, nil, rollup)
+	return rollup, err
+}
+
+func (c *Client) VerifyRollup(ctx context.Context, name string) (*types.RollupVerification, error) {
+	verification := &types.RollupVerification{}
+	err := c.do(ctx, "verifyRollup", map[string]string{"id": name}, nil, nil, verification)
+	return verification, err
+}
+
+func (c *Client) Faucet(ctx context.Context, name, address string) (*types.FaucetDrip, error) {
+	drip := &types.FaucetDrip{}
+	req := &types.FaucetRequest{Address: address}
+	err := c.do(ctx, "requestFaucet", map[string]string{"id": name}, nil, req, drip)
+	return drip, err
+}
+
+func (c *Client) FundWallets(ctx context.Context, name string, wallets []types.L2FundWallets) ([]types.WalletTransfer, error) {
+	transfers := []types.WalletTransfer{}
+	req := &types.FundWalletsRequest{Wallets: wallets}
+	err := c.do(ctx, "fundWallets", map[string]string{"id": name}, nil, req, &transfers)
+	return transfers, err
+}
+
+func (c *Client) ListWallets(ctx context.Context, name string) ([]types.WalletBalance, error) {
+	balances := []types.WalletBalance{}
+	err := c.do(ctx, "listWallets", map[string]string{"id": name}, nil, nil, &balances)
+	return balances, err
+}
+
+func (c *Client) ChainMetadata(ctx context.Context, name string) (*types.ChainMetadata, error) {
+	metadata := &types.ChainMetadata{}
+	err := c.do(ctx, "getChainMetadata", map[string]string{"id": name}, nil, nil, metadata)
+	return metadata, err
+}
+
+func (c *Client) Contracts(ctx context.Context, name string) (*types.RollupContractRegistry, error) {
+	registry := &types.RollupContractRegistry{}
+	err := c.do(ctx, "listContracts", map[string]string{"id": name}, nil, nil, registry)
+	return registry, err
+}
+
+func (c *Client) BackupRollup(ctx context.Context, name string) (*types.RollupBackup, error) {
+	backup := &types.RollupBackup{}
+	err := c.do(ctx, "backupRollup", map[string]string{"id": name}, nil, nil, backup)
+	return backup, err
+}
+
+func (c *Client) ListBackups(ctx context.Context, name string) ([]*types.RollupBackup, error) {
+	backups := []*types.RollupBackup{}
+	err := c.do(ctx, "listBackups", map[string]string{"id": name}, nil, nil, &backups)
+	return backups, err
+}
+
+// RestoreRollup recreates a rollup from an archive file in the backups dir of
+// the server, under name unless it is empty.
+func (c *Client) RestoreRollup(ctx context.Context, file, name string) (*types.Rollup, error) {
+	rollup := &types.Rollup{}
+	req := &types.RestoreRollupRequest{File: file, Name: name}
+	err := c.do(ctx, "restoreRollup", nil, nil, req, rollup)
+	return rollup, err
+}
+
+// DeleteRollup removes a rollup with its chain data, confirm must repeat its
+// name.
+func (c *Client) DeleteRollup(ctx context.Context, name, confirm string) error {
+	query := url.Values{}
+	query.Set("confirm", confirm)
+	return c.do(ctx, "deleteRollup", map[string]string{"id": name}, query, nil, nil)
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	operationId string,
+	params map[string]string,
+	query url.Values,
+	body interface{},
+	out interface{},
+) error {
+	op, err := api.OperationById(operationId)
+	if err != nil {
+		return err
+	}
+	p := op.Path
+	for k, v := range params {
+		p = strings.ReplaceAll(p, "{"+k+"}", url.PathEscape(v))
+	}
+	u := c.baseUrl + p
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+	req, err := http.NewRequestWithContext(ctx, op.Method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(content))}
+	}
+	if resp.StatusCode >= http.StatusBadRequest || env.Code == types.ResponseCodeFailed {
+		return &Error{StatusCode: resp.StatusCode, Message: env.Message}
+	}
+	if out == nil || len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/g1g2-lab/automation/client"
+	"github.com/g1g2-lab/automation/pkg/bridge"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+var (
+	bridgeFlagSet         = flag.NewFlagSet("g1g2 bridge", flag.ExitOnError)
+	bridgeServerFlag      = bridgeFlagSet.String("server", defaultServerUrl, "rollup server url (env G1G2_SERVER_URL)")
+	bridgeKeyFlag         = bridgeFlagSet.String("key", defaultFunderKey, "private key funding the transfers, on l1 or on l2 for withdrawals (env G1G2_FUNDER_KEY)")
+	bridgeModeFlag        = bridgeFlagSet.String("mode", bridge.ModeRoundTrip, "deposit, withdraw or roundtrip")
+	bridgeAmountFlag      = bridgeFlagSet.String("amount", "100000000000000000", "wei deposited, or withdrawn in withdraw mode")
+	bridgeRelayFeeFlag    = bridgeFlagSet.String("relay-fee", "20000000000000000", "relay fee in wei paid with each message")
+	bridgeTransfersFlag   = bridgeFlagSet.Int("transfers", 1, "number of transfers, each from a fresh account")
+	bridgeConcurrencyFlag = bridgeFlagSet.Int("concurrency", 1, "transfers in flight at once")
+	bridgeTimeoutFlag     = bridgeFlagSet.Duration("timeout", 10*time.Minute, "how long the relayer may take to deliver a message")
+	bridgeOutputFlag      = bridgeFlagSet.String("output", "table", "output format of the report: table or json")
+	bridgeCommand         = &ffcli.Command{
+		Name:       "bridge",
+		ShortUsage: "g1g2 bridge [flags] <rollup>",
+		ShortHelp:  "🌟send eth through the escrows of a rollup and time the relayer",
+		LongHelp:   "",
+
+		FlagSet: bridgeFlagSet,
+		Exec:    bridgeMain,
+	}
+)
+
+func bridgeMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := client.New(*bridgeServerFlag).GetRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	amount, ok := new(big.Int).SetString(*bridgeAmountFlag, 10)
+	if !ok {
+		return fmt.Errorf("--amount must be an amount in wei, got %q", *bridgeAmountFlag)
+	}
+	relayFee, ok := new(big.Int).SetString(*bridgeRelayFeeFlag, 10)
+	if !ok {
+		return fmt.Errorf("--relay-fee must be an amount in wei, got %q", *bridgeRelayFeeFlag)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-getQuitCh()
+		cancel()
+	}()
+	harness, err := bridge.New(ctx, bridge.Config{
+		L1RpcUrl:    rollup.L1.PublicRpcUrl,
+		L2RpcUrl:    rollup.RpcUrl,
+		L1Escrow:    common.HexToAddress(rollup.L1Escrow),
+		L2Escrow:    common.HexToAddress(rollup.L2Escrow),
+		Key:         *bridgeKeyFlag,
+		Mode:        *bridgeModeFlag,
+		Amount:      amount,
+		RelayFee:    relayFee,
+		Transfers:   *bridgeTransfersFlag,
+		Concurrency: *bridgeConcurrencyFlag,
+		Timeout:     *bridgeTimeoutFlag,
+	})
+	if err != nil {
+		return err
+	}
+	report, err := harness.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if *bridgeOutputFlag == "json" {
+		return printJSON(report)
+	}
+	return printBridgeReport(report)
+}
+
+func printBridgeReport(r *bridge.Report) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LEG\tDELIVERED\tFAILED\tINCLUSION P50/P90/MAX\tRELAY P50/P90/MAX\tFINALIZATION P50/P90/MAX")
+	legs := []string{}
+	for leg := range r.Legs {
+		legs = append(legs, leg)
+	}
+	sort.Strings(legs)
+	for _, name := range legs {
+		leg := r.Legs[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", name, leg.Delivered, leg.Failed,
+			formatPercentiles(leg.Inclusion), formatPercentiles(leg.Relay), formatPercentiles(leg.Finalization))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d %s transfers, %d at once, in %s\n", r.Transfers, r.Mode, r.Concurrency, r.End.Sub(r.Start).Round(time.Second))
+	for _, name := range legs {
+		for msg, count := range r.Legs[name].Errors {
+			fmt.Printf("%s error: %dx %s\n", name, count, msg)
+		}
+	}
+	return nil
+}
+
+func formatPercentiles(p bridge.Percentiles) string {
+	return fmt.Sprintf("%s / %s / %s", p.P50.Round(time.Second), p.P90.Round(time.Second), p.Max.Round(time.Second))
+}
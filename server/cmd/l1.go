@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/g1g2-lab/automation/l1"
+	"github.com/g1g2-lab/automation/l2"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+var (
+	l1FlagSet = flag.NewFlagSet("g1g2 l1", flag.ExitOnError)
+	l1Command = &ffcli.Command{
+		Name:       "l1",
+		ShortUsage: "g1g2 l1 <subcommand>",
+		ShortHelp:  "run the local l1 dev chain the rollups settle on",
+		FlagSet:    l1FlagSet,
+		Exec: func(ctx context.Context, args []string) error {
+			return flag.ErrHelp
+		},
+		Subcommands: []*ffcli.Command{
+			l1UpCommand,
+			l1DownCommand,
+			l1StatusCommand,
+			l1ResetCommand,
+			l1FundCommand,
+			l1MineCommand,
+		},
+	}
+
+	l1UpFlagSet, l1UpFlags = newL1FlagSet("g1g2 l1 up")
+	l1UpCommand            = &ffcli.Command{
+		Name:       "up",
+		ShortUsage: "g1g2 l1 up [flags]",
+		ShortHelp:  "generate the genesis, start geth and the explorer and register the l1",
+		FlagSet:    l1UpFlagSet,
+		Exec:       l1UpMain,
+	}
+
+	l1DownFlagSet    = flag.NewFlagSet("g1g2 l1 down", flag.ExitOnError)
+	l1DownConfigFlag = l1DownFlagSet.String("config", "rollup_server_prod.yaml", "g1g2 configuration file, see l1_dev")
+	l1DownCommand    = &ffcli.Command{
+		Name:       "down",
+		ShortUsage: "g1g2 l1 down [flags]",
+		ShortHelp:  "stop the l1 and keep its data",
+		FlagSet:    l1DownFlagSet,
+		Exec:       l1DownMain,
+	}
+
+	l1StatusFlagSet    = flag.NewFlagSet("g1g2 l1 status", flag.ExitOnError)
+	l1StatusOutputFlag = l1StatusFlagSet.String("output", "table", "output format: table or json")
+	l1StatusCommand    = &ffcli.Command{
+		Name:       "status",
+		ShortUsage: "g1g2 l1 status [flags]",
+		ShortHelp:  "show the registered l1 networks and whether they serve blocks",
+		FlagSet:    l1StatusFlagSet,
+		Exec:       l1StatusMain,
+	}
+
+	l1ResetFlagSet, l1ResetFlags = newL1FlagSet("g1g2 l1 reset")
+	l1ResetConfirmFlag           = l1ResetFlagSet.String("confirm", "", "name of the l1, the rollup contracts deployed on it are lost")
+	l1ResetCommand               = &ffcli.Command{
+		Name:       "reset",
+		ShortUsage: "g1g2 l1 reset --confirm <name> [flags]",
+		ShortHelp:  "remove the l1 with its data and start a new chain",
+		FlagSet:    l1ResetFlagSet,
+		Exec:       l1ResetMain,
+	}
+
+	l1FundFlagSet    = flag.NewFlagSet("g1g2 l1 fund", flag.ExitOnError)
+	l1FundConfigFlag = l1FundFlagSet.String("config", "rollup_server_prod.yaml", "g1g2 configuration file")
+	l1FundNetFlag    = l1FundFlagSet.String("l1", "", "registered l1 to fund on, the dev l1 by default")
+	l1FundCommand    = &ffcli.Command{
+		Name:       "fund",
+		ShortUsage: "g1g2 l1 fund [flags] <address> <amount in wei>",
+		ShortHelp:  "add l1 funds to an account",
+		FlagSet:    l1FundFlagSet,
+		Exec:       l1FundMain,
+	}
+
+	l1MineFlagSet    = flag.NewFlagSet("g1g2 l1 mine", flag.ExitOnError)
+	l1MineConfigFlag = l1MineFlagSet.String("config", "rollup_server_prod.yaml", "g1g2 configuration file")
+	l1MineNetFlag    = l1MineFlagSet.String("l1", "", "registered l1 to mine on, the dev l1 by default")
+	l1MineBlocksFlag = l1MineFlagSet.Int("blocks", 1, "blocks to mine")
+	l1MineCommand    = &ffcli.Command{
+		Name:       "mine",
+		ShortUsage: "g1g2 l1 mine [flags]",
+		ShortHelp:  "mine l1 blocks now, or wait for them on an l1 sealing on its own period",
+		FlagSet:    l1MineFlagSet,
+		Exec:       l1MineMain,
+	}
+)
+
+// l1Flags override the l1_dev settings of the config file.
+type l1Flags struct {
+	fs       *flag.FlagSet
+	config   *string
+	provider *string
+	chainId  *int
+	period   *int
+	httpPort *int
+	explorer *bool
+	accounts *walletsFlag
+}
+
+func newL1FlagSet(name string) (*flag.FlagSet, *l1Flags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	f := &l1Flags{
+		fs:       fs,
+		config:   fs.String("config", "rollup_server_prod.yaml", "g1g2 configuration file, see l1_dev"),
+		provider: fs.String("provider", "", "run the l1 with docker, anvil or geth-dev"),
+		chainId:  fs.Int("chain-id", 0, "l1 chain id"),
+		period:   fs.Int("period", 0, "seconds between l1 blocks"),
+		httpPort: fs.Int("http-port", 0, "host port of the http rpc, ws is http-port+1"),
+		explorer: fs.Bool("explorer", false, "also run a blockscout explorer"),
+		accounts: &walletsFlag{},
+	}
+	fs.Var(f.accounts, "account", "account prefunded in the genesis as <address>=<amount in wei>, repeatable")
+	return fs, f
+}
+
+func (f *l1Flags) devConfig() (*l1.DevConfig, string, error) {
+	l2Config, err := l2.NewL2ConfigFromFile(*f.config)
+	if err != nil {
+		return nil, "", err
+	}
+	cfg := l2Config.L1Dev
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "provider":
+			cfg.Provider = *f.provider
+		case "chain-id":
+			cfg.ChainId = *f.chainId
+		case "period":
+			cfg.Period = *f.period
+		case "http-port":
+			cfg.HttpPort = *f.httpPort
+		case "explorer":
+			cfg.Explorer = *f.explorer
+		}
+	})
+	for _, account := range *f.accounts {
+		if cfg.Accounts == nil {
+			cfg.Accounts = map[string]string{}
+		}
+		cfg.Accounts[account.WalletAddress] = account.AmountInWei
+	}
+	return &cfg, l2Config.TemplateConfig.OverrideDir, nil
+}
+
+// l1Provider returns the provider of the registered l1 name, the dev l1 of
+// the config when name is empty.
+func l1Provider(config string, name string) (l1.Provider, error) {
+	l2Config, err := l2.NewL2ConfigFromFile(config)
+	if err != nil {
+		return nil, err
+	}
+	net, err := l1.ResolveNetwork(types.L1Net{Name: name}, &l2Config.L1Dev)
+	if err != nil {
+		return nil, err
+	}
+	return l2.NewL1Provider(l2Config, net)
+}
+
+func l1UpMain(ctx context.Context, args []string) error {
+	cfg, overrideDir, err := l1UpFlags.devConfig()
+	if err != nil {
+		return err
+	}
+	net, err := l1.Up(ctx, cfg, overrideDir)
+	if err != nil {
+		return err
+	}
+	printL1Net(net)
+	return nil
+}
+
+func l1DownMain(ctx context.Context, args []string) error {
+	l2Config, err := l2.NewL2ConfigFromFile(*l1DownConfigFlag)
+	if err != nil {
+		return err
+	}
+	return l1.Down(&l2Config.L1Dev, l2Config.TemplateConfig.OverrideDir)
+}
+
+func l1ResetMain(ctx context.Context, args []string) error {
+	cfg, overrideDir, err := l1ResetFlags.devConfig()
+	if err != nil {
+		return err
+	}
+	if *l1ResetConfirmFlag != cfg.Name {
+		return fmt.Errorf("resetting l1 %s removes its chain and the rollup contracts deployed on it, confirm with its name", cfg.Name)
+	}
+	net, err := l1.Reset(ctx, cfg, overrideDir)
+	if err != nil {
+		return err
+	}
+	printL1Net(net)
+	return nil
+}
+
+func l1FundMain(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected an address and an amount in wei, got %d arguments", len(args))
+	}
+	if !common.IsHexAddress(args[0]) {
+		return fmt.Errorf("invalid address %q", args[0])
+	}
+	amount, ok := new(big.Int).SetString(args[1], 10)
+	if !ok || amount.Sign() <= 0 {
+		return fmt.Errorf("amount must be a positive amount in wei, got %q", args[1])
+	}
+	provider, err := l1Provider(*l1FundConfigFlag, *l1FundNetFlag)
+	if err != nil {
+		return err
+	}
+	address := common.HexToAddress(args[0])
+	if err := provider.Fund(ctx, address, amount); err != nil {
+		return err
+	}
+	fmt.Printf("funded %s with %s wei on l1 %s\n", address, amount, provider.Network().Name)
+	return nil
+}
+
+func l1MineMain(ctx context.Context, args []string) error {
+	if *l1MineBlocksFlag <= 0 {
+		return fmt.Errorf("--blocks must be positive, got %d", *l1MineBlocksFlag)
+	}
+	provider, err := l1Provider(*l1MineConfigFlag, *l1MineNetFlag)
+	if err != nil {
+		return err
+	}
+	if err := provider.Mine(ctx, *l1MineBlocksFlag); err != nil {
+		return err
+	}
+	fmt.Printf("mined %d blocks on l1 %s\n", *l1MineBlocksFlag, provider.Network().Name)
+	return nil
+}
+
+func l1StatusMain(ctx context.Context, args []string) error {
+	networks, err := l1.Networks()
+	if err != nil {
+		return err
+	}
+	statuses := []*l1.Status{}
+	for _, net := range networks {
+		statuses = append(statuses, l1.NetworkStatus(ctx, net))
+	}
+	if *l1StatusOutputFlag == "json" {
+		return printJSON(statuses)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPROVIDER\tCHAIN ID\tRPC\tRUNNING\tHEAD\tHEAD AGE\tEXPLORER\tERROR")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%t\t%d\t%s\t%s\t%s\n", s.Network.Name, s.Network.Provider, s.Network.ChainId, s.Network.PublicRpcUrl,
+			s.Running, s.Head, s.HeadAge, s.Network.ExplorerUrl, s.Error)
+	}
+	return w.Flush()
+}
+
+func printL1Net(net *types.L1Net) {
+	fmt.Printf("l1 %s is running, chain id %d\n", net.Name, net.ChainId)
+	fmt.Printf("  rpc: %s\n", net.PublicRpcUrl)
+	fmt.Printf("  ws: %s\n", net.PublicWsUrl)
+	if net.ExplorerUrl != "" {
+		fmt.Printf("  explorer: %s\n", net.ExplorerUrl)
+	}
+}
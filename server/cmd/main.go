@@ -21,8 +21,10 @@ var (
 		},
 		Subcommands: []*ffcli.Command{
 			rollupCommand,
+			l1Command,
 			genesisCommand,
 			genTxsCommand,
+			bridgeCommand,
 		},
 	}
 )
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/g1g2-lab/automation/l2"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+var (
+	applyFlagSet    = flag.NewFlagSet("g1g2 rollup apply", flag.ExitOnError)
+	applyFileFlag   = applyFlagSet.String("f", "", "rollup spec file (yaml or json)")
+	applyConfigFlag = applyFlagSet.String("config", "rollup_server_prod.yaml", "g1g2 configuration file")
+	applyDbFlag     = applyFlagSet.String("db", "build/db", "rollup record dir, shared with the rollup server")
+	applyCommand    = &ffcli.Command{
+		Name:       "apply",
+		ShortUsage: "g1g2 rollup apply -f spec.yaml",
+		ShortHelp:  "create or update a rollup from a spec without a server",
+		FlagSet:    applyFlagSet,
+		Exec:       applyMain,
+	}
+)
+
+func applyMain(ctx context.Context, args []string) error {
+	if *applyFileFlag == "" {
+		return fmt.Errorf("-f is required")
+	}
+	spec, err := types.NewRollupSpecFromFile(*applyFileFlag)
+	if err != nil {
+		return err
+	}
+	l2Config, err := l2.NewL2ConfigFromFile(*applyConfigFlag)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(*applyDbFlag, 0755)
+	if err != nil {
+		return err
+	}
+	// Unlike the server, apply keeps the records: they are the state the
+	// next apply reconciles against.
+	db := db.NewLocalDatabase(ctx, *applyDbFlag)
+
+	result, err := l2.ApplyRollup(ctx, l2Config, spec, db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case result.Created:
+		fmt.Printf("rollup %s created, rpc %s\n", spec.Name, result.Rollup.RpcUrl)
+		if result.Rollup.ExplorerUrl != "" {
+			fmt.Printf("explorer %s\n", result.Rollup.ExplorerUrl)
+		}
+	case result.UpToDate():
+		fmt.Printf("rollup %s is up to date\n", spec.Name)
+	default:
+		fmt.Printf("rollup %s updated\n", spec.Name)
+		fmt.Printf("  changed artifacts: %s\n", strings.Join(result.Changed, ", "))
+		fmt.Printf("  rebuilt services: %s\n", strings.Join(result.Services, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,793 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/g1g2-lab/automation/client"
+	"github.com/g1g2-lab/automation/pkg/env"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+var (
+	defaultServerUrl = env.Get("G1G2_SERVER_URL", "http://127.0.0.1:8082", "rollup server url used by the rollup cli subcommands")
+)
+
+type cliFlags struct {
+	server *string
+	output *string
+}
+
+func newCliFlagSet(name string) (*flag.FlagSet, *cliFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	return fs, &cliFlags{
+		server: fs.String("server", defaultServerUrl, "rollup server url (env G1G2_SERVER_URL)"),
+		output: fs.String("output", "table", "output format: table or json"),
+	}
+}
+
+func (f *cliFlags) client() *client.Client {
+	return client.New(*f.server)
+}
+
+type walletsFlag []types.L2FundWallets
+
+func (w *walletsFlag) String() string {
+	wallets := []string{}
+	for _, wallet := range *w {
+		wallets = append(wallets, wallet.WalletAddress+"="+wallet.AmountInWei)
+	}
+	return strings.Join(wallets, ",")
+}
+
+func (w *walletsFlag) Set(s string) error {
+	address, amount, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("wallet must be <address>=<amount in wei>, got %q", s)
+	}
+	*w = append(*w, types.L2FundWallets{WalletAddress: address, AmountInWei: amount})
+	return nil
+}
+
+var (
+	createFlagSet, createFlags = newCliFlagSet("g1g2 rollup create")
+	createChainIdFlag          = createFlagSet.Int("chain-id", 0, "l2 chain id")
+	createBeneficiaryFlag      = createFlagSet.String("beneficiary", "", "proposer beneficiary address")
+	createReplicasFlag         = createFlagSet.Int("replicas", 0, "replica nodes behind a load balanced rpc")
+	createWaitFlag             = createFlagSet.Bool("wait", false, "follow provisioning until the rollup is online or fails")
+	createDryRunFlag           = createFlagSet.Bool("dry-run", false, "only render the artifacts on the server and print the plan")
+	createIconFlag             = createFlagSet.String("icon", "", "url of the icon shown by wallets")
+	createCurrencyNameFlag     = createFlagSet.String("currency-name", "", "name of the native currency, Ether by default")
+	createCurrencySymbolFlag   = createFlagSet.String("currency-symbol", "", "symbol of the native currency, ETH by default")
+	createCurrencyDecimalsFlag = createFlagSet.Int("currency-decimals", 0, "decimals of the native currency, 18 by default")
+	createWalletsFlag          = walletsFlag{}
+	createCommand              = &ffcli.Command{
+		Name:       "create",
+		ShortUsage: "g1g2 rollup create [flags] <name>",
+		ShortHelp:  "create a rollup through the rollup server",
+		FlagSet:    createFlagSet,
+		Exec:       createMain,
+	}
+
+	listFlagSet, listFlags = newCliFlagSet("g1g2 rollup list")
+	listCommand            = &ffcli.Command{
+		Name:       "list",
+		ShortUsage: "g1g2 rollup list [flags]",
+		ShortHelp:  "list rollups",
+		FlagSet:    listFlagSet,
+		Exec:       listMain,
+	}
+
+	getFlagSet, getFlags = newCliFlagSet("g1g2 rollup get")
+	getCommand           = &ffcli.Command{
+		Name:       "get",
+		ShortUsage: "g1g2 rollup get [flags] <name>",
+		ShortHelp:  "show a rollup",
+		FlagSet:    getFlagSet,
+		Exec:       getMain,
+	}
+
+	deleteFlagSet, deleteFlags = newCliFlagSet("g1g2 rollup delete")
+	deleteConfirmFlag          = deleteFlagSet.String("confirm", "", "repeat the rollup name to confirm removing its chain data")
+	deleteCommand              = &ffcli.Command{
+		Name:       "delete",
+		ShortUsage: "g1g2 rollup delete --confirm <name> [flags] <name>",
+		ShortHelp:  "remove a rollup with its chain data and delete its record",
+		FlagSet:    deleteFlagSet,
+		Exec:       deleteMain,
+	}
+
+	stopFlagSet, stopFlags = newCliFlagSet("g1g2 rollup stop")
+	stopCommand            = &ffcli.Command{
+		Name:       "stop",
+		ShortUsage: "g1g2 rollup stop [flags] <name>",
+		ShortHelp:  "stop the containers of a rollup, keeping its data",
+		FlagSet:    stopFlagSet,
+		Exec:       stopMain,
+	}
+
+	startFlagSet, startFlags = newCliFlagSet("g1g2 rollup start")
+	startCommand             = &ffcli.Command{
+		Name:       "start",
+		ShortUsage: "g1g2 rollup start [flags] <name>",
+		ShortHelp:  "bring a stopped or archived rollup back online",
+		FlagSet:    startFlagSet,
+		Exec:       startMain,
+	}
+
+	archiveFlagSet, archiveFlags = newCliFlagSet("g1g2 rollup archive")
+	archiveCommand               = &ffcli.Command{
+		Name:       "archive",
+		ShortUsage: "g1g2 rollup archive [flags] <name>",
+		ShortHelp:  "remove the containers of a rollup and free its ports, keeping its data",
+		FlagSet:    archiveFlagSet,
+		Exec:       archiveMain,
+	}
+
+	forkFlagSet, forkFlags = newCliFlagSet("g1g2 rollup fork")
+	forkNameFlag           = forkFlagSet.String("name", "", "name of the fork")
+	forkChainIdFlag        = forkFlagSet.Int("chain-id", 0, "l2 chain id of the fork")
+	forkBlockFlag          = forkFlagSet.Int64("block", -1, "source block to fork at, the head when negative")
+	forkBasePortFlag       = forkFlagSet.Int("base-port", 0, "base host port of the fork, after the highest one in use when 0")
+	forkWaitFlag           = forkFlagSet.Bool("wait", false, "follow provisioning until the fork is online or fails")
+	forkCommand            = &ffcli.Command{
+		Name:       "fork",
+		ShortUsage: "g1g2 rollup fork --name <fork> --chain-id <id> [flags] <source>",
+		ShortHelp:  "start a new rollup from the state of another one at a block",
+		FlagSet:    forkFlagSet,
+		Exec:       forkMain,
+	}
+
+	backupFlagSet, backupFlags = newCliFlagSet("g1g2 rollup backup")
+	backupCommand              = &ffcli.Command{
+		Name:       "backup",
+		ShortUsage: "g1g2 rollup backup [flags] <name>",
+		ShortHelp:  "archive the record, build dir and volumes of a rollup on the server host",
+		FlagSet:    backupFlagSet,
+		Exec:       backupMain,
+	}
+
+	backupsFlagSet, backupsFlags = newCliFlagSet("g1g2 rollup backups")
+	backupsCommand               = &ffcli.Command{
+		Name:       "backups",
+		ShortUsage: "g1g2 rollup backups [flags] <name>",
+		ShortHelp:  "list the backups of a rollup",
+		FlagSet:    backupsFlagSet,
+		Exec:       backupsMain,
+	}
+
+	restoreFlagSet, restoreFlags = newCliFlagSet("g1g2 rollup restore")
+	restoreFileFlag              = restoreFlagSet.String("file", "", "backup archive in the backups dir of the server, as listed by backups")
+	restoreNameFlag              = restoreFlagSet.String("name", "", "restore the rollup under this name instead of its own")
+	restoreCommand               = &ffcli.Command{
+		Name:       "restore",
+		ShortUsage: "g1g2 rollup restore --file <archive> [flags]",
+		ShortHelp:  "recreate and start a rollup from a backup",
+		FlagSet:    restoreFlagSet,
+		Exec:       restoreMain,
+	}
+
+	verifyFlagSet, verifyFlags = newCliFlagSet("g1g2 rollup verify")
+	verifyCommand              = &ffcli.Command{
+		Name:       "verify",
+		ShortUsage: "g1g2 rollup verify [flags] <name>",
+		ShortHelp:  "check end to end that a running rollup works",
+		FlagSet:    verifyFlagSet,
+		Exec:       verifyMain,
+	}
+
+	faucetFlagSet, faucetFlags = newCliFlagSet("g1g2 rollup faucet")
+	faucetCommand              = &ffcli.Command{
+		Name:       "faucet",
+		ShortUsage: "g1g2 rollup faucet [flags] <name> <address>",
+		ShortHelp:  "send the faucet amount of a rollup to an address",
+		FlagSet:    faucetFlagSet,
+		Exec:       faucetMain,
+	}
+
+	walletsFlagSet, walletsFlags = newCliFlagSet("g1g2 rollup wallets")
+	walletsFundFlag              = walletsFlag{}
+	walletsCommand               = &ffcli.Command{
+		Name:       "wallets",
+		ShortUsage: "g1g2 rollup wallets [--fund <address>=<amount>]... [flags] <name>",
+		ShortHelp:  "list the funded wallets of a rollup with their balances, or fund wallets",
+		FlagSet:    walletsFlagSet,
+		Exec:       walletsMain,
+	}
+
+	chainFlagSet, chainFlags = newCliFlagSet("g1g2 rollup chain")
+	chainDocFlag             = chainFlagSet.String("doc", "", "print only one document: wallet or chain-list")
+	chainCommand             = &ffcli.Command{
+		Name:       "chain",
+		ShortUsage: "g1g2 rollup chain [flags] <name>",
+		ShortHelp:  "print the wallet_addEthereumChain parameter and ethereum-lists entry of a rollup",
+		FlagSet:    chainFlagSet,
+		Exec:       chainMain,
+	}
+
+	contractsFlagSet, contractsFlags = newCliFlagSet("g1g2 rollup contracts")
+	contractsCommand                 = &ffcli.Command{
+		Name:       "contracts",
+		ShortUsage: "g1g2 rollup contracts [flags] <name>",
+		ShortHelp:  "list the l1 and l2 contracts of a rollup and check their code on chain, --output json adds the abis",
+		FlagSet:    contractsFlagSet,
+		Exec:       contractsMain,
+	}
+
+	logsFlagSet, logsFlags = newCliFlagSet("g1g2 rollup logs")
+	logsServiceFlag        = logsFlagSet.String("service", "", "compose service, all services when empty")
+	logsTailFlag           = logsFlagSet.Int("tail", 100, "number of lines per service")
+	logsCommand            = &ffcli.Command{
+		Name:       "logs",
+		ShortUsage: "g1g2 rollup logs [flags] <name>",
+		ShortHelp:  "print container logs of a rollup",
+		FlagSet:    logsFlagSet,
+		Exec:       logsMain,
+	}
+
+	statusFlagSet, statusFlags = newCliFlagSet("g1g2 rollup status")
+	statusWaitFlag             = statusFlagSet.Bool("wait", false, "follow provisioning until the rollup is online or fails")
+	statusCommand              = &ffcli.Command{
+		Name:       "status",
+		ShortUsage: "g1g2 rollup status [flags] <name>",
+		ShortHelp:  "show provisioning progress of a rollup",
+		FlagSet:    statusFlagSet,
+		Exec:       statusMain,
+	}
+
+	proverFlagSet, proverFlags = newCliFlagSet("g1g2 rollup prover")
+	proverCommand              = &ffcli.Command{
+		Name:       "prover",
+		ShortUsage: "g1g2 rollup prover [flags] <name>",
+		ShortHelp:  "show prover status and proof lag of a rollup",
+		FlagSet:    proverFlagSet,
+		Exec:       proverMain,
+	}
+
+	rotateJwtFlagSet, rotateJwtFlags = newCliFlagSet("g1g2 rollup rotate-jwt")
+	rotateJwtCommand                 = &ffcli.Command{
+		Name:       "rotate-jwt",
+		ShortUsage: "g1g2 rollup rotate-jwt [flags] <name>",
+		ShortHelp:  "replace the engine api secret of a rollup and restart its nodes",
+		FlagSet:    rotateJwtFlagSet,
+		Exec:       rotateJwtMain,
+	}
+
+	upgradeFlagSet, upgradeFlags = newCliFlagSet("g1g2 rollup upgrade")
+	upgradeVersionFlag           = upgradeFlagSet.Int("version", 0, "target version of the rollup contracts")
+	upgradeCommand               = &ffcli.Command{
+		Name:       "upgrade",
+		ShortUsage: "g1g2 rollup upgrade --version <n> [flags] <name>",
+		ShortHelp:  "upgrade the l1 and l2 rollup contracts of a rollup",
+		FlagSet:    upgradeFlagSet,
+		Exec:       upgradeMain,
+	}
+
+	upgradeImageFlagSet, upgradeImageFlags = newCliFlagSet("g1g2 rollup upgrade-image")
+	upgradeImageFlag                       = upgradeImageFlagSet.String("image", "", "image to upgrade: execution or consensus")
+	upgradeImageVersionFlag                = upgradeImageFlagSet.String("version", "", "new image version, e.g. 1.0.8-g1g2")
+	upgradeImageCommand                    = &ffcli.Command{
+		Name:       "upgrade-image",
+		ShortUsage: "g1g2 rollup upgrade-image --image <execution|consensus> --version <v> [flags] <name>",
+		ShortHelp:  "upgrade the execution or consensus client of a rollup, rolling back when unhealthy",
+		FlagSet:    upgradeImageFlagSet,
+		Exec:       upgradeImageMain,
+	}
+
+	scaleFlagSet, scaleFlags = newCliFlagSet("g1g2 rollup scale")
+	scaleReplicasFlag        = scaleFlagSet.Int("replicas", 0, "number of replica nodes")
+	scaleCommand             = &ffcli.Command{
+		Name:       "scale",
+		ShortUsage: "g1g2 rollup scale --replicas <n> [flags] <name>",
+		ShortHelp:  "scale the replica nodes of a running rollup",
+		FlagSet:    scaleFlagSet,
+		Exec:       scaleMain,
+	}
+)
+
+func init() {
+	createFlagSet.Var(&createWalletsFlag, "wallet", "l2 wallet funded in genesis as <address>=<amount in wei>, repeatable")
+	walletsFlagSet.Var(&walletsFundFlag, "fund", "send <amount in wei> to <address> from the rollup admin as <address>=<amount in wei>, repeatable")
+}
+
+func nameArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected exactly one rollup name, got %d arguments", len(args))
+	}
+	return args[0], nil
+}
+
+func createMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	req := &types.CreateRollupRequest{
+		Name:               name,
+		ChainId:            *createChainIdFlag,
+		BeneficiaryAddress: *createBeneficiaryFlag,
+		L2FundWallets:      createWalletsFlag,
+		Replicas:           *createReplicasFlag,
+		Icon:               *createIconFlag,
+		NativeCurrency: types.NativeCurrency{
+			Name:     *createCurrencyNameFlag,
+			Symbol:   *createCurrencySymbolFlag,
+			Decimals: *createCurrencyDecimalsFlag,
+		},
+	}
+	if *createDryRunFlag {
+		plan, err := createFlags.client().PlanRollup(ctx, req)
+		if err != nil {
+			return err
+		}
+		if *createFlags.output == "json" {
+			return printJSON(plan)
+		}
+		return printPlan(plan)
+	}
+	job, err := createFlags.client().CreateRollup(ctx, req)
+	if err != nil {
+		return err
+	}
+	if *createWaitFlag {
+		return waitRollup(ctx, createFlags, name)
+	}
+	return printJob(createFlags, job)
+}
+
+func listMain(ctx context.Context, args []string) error {
+	rollups, err := listFlags.client().ListRollups(ctx)
+	if err != nil {
+		return err
+	}
+	return printRollups(listFlags, rollups)
+}
+
+func getMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := getFlags.client().GetRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	if *getFlags.output == "json" {
+		return printJSON(rollup)
+	}
+	return printRollups(getFlags, []*types.Rollup{rollup})
+}
+
+func deleteMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	if err := deleteFlags.client().DeleteRollup(ctx, name, *deleteConfirmFlag); err != nil {
+		return err
+	}
+	fmt.Printf("rollup %s deleted\n", name)
+	return nil
+}
+
+func stopMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := stopFlags.client().StopRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	return printRollups(stopFlags, []*types.Rollup{rollup})
+}
+
+func startMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := startFlags.client().StartRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	return printRollups(startFlags, []*types.Rollup{rollup})
+}
+
+func archiveMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := archiveFlags.client().ArchiveRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	return printRollups(archiveFlags, []*types.Rollup{rollup})
+}
+
+func forkMain(ctx context.Context, args []string) error {
+	source, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	req := &types.ForkRollupRequest{
+		Name:     *forkNameFlag,
+		ChainId:  *forkChainIdFlag,
+		BasePort: *forkBasePortFlag,
+	}
+	if *forkBlockFlag >= 0 {
+		block := uint64(*forkBlockFlag)
+		req.Block = &block
+	}
+	job, err := forkFlags.client().ForkRollup(ctx, source, req)
+	if err != nil {
+		return err
+	}
+	if *forkWaitFlag {
+		return waitRollup(ctx, forkFlags, req.Name)
+	}
+	return printJob(forkFlags, job)
+}
+
+func backupMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	backup, err := backupFlags.client().BackupRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	return printBackups(backupFlags, []*types.RollupBackup{backup})
+}
+
+func backupsMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	backups, err := backupsFlags.client().ListBackups(ctx, name)
+	if err != nil {
+		return err
+	}
+	return printBackups(backupsFlags, backups)
+}
+
+func restoreMain(ctx context.Context, args []string) error {
+	if *restoreFileFlag == "" {
+		return fmt.Errorf("--file is required")
+	}
+	rollup, err := restoreFlags.client().RestoreRollup(ctx, *restoreFileFlag, *restoreNameFlag)
+	if err != nil {
+		return err
+	}
+	return printRollups(restoreFlags, []*types.Rollup{rollup})
+}
+
+func printBackups(f *cliFlags, backups []*types.RollupBackup) error {
+	if *f.output == "json" {
+		return printJSON(backups)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tSIZE\tVOLUMES\tFILE")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", b.Name, b.CreatedAt.Format(time.RFC3339), b.Size, strings.Join(b.Volumes, ","), b.File)
+	}
+	return w.Flush()
+}
+
+func verifyMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	verification, err := verifyFlags.client().VerifyRollup(ctx, name)
+	if err != nil {
+		return err
+	}
+	if *verifyFlags.output == "json" {
+		err = printJSON(verification)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tRESULT\tDURATION\tDETAIL")
+		for _, c := range verification.Checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.Result, c.Duration.Round(time.Millisecond), c.Detail)
+		}
+		err = w.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if !verification.Passed {
+		return fmt.Errorf("rollup %s failed verification: %s", name, strings.Join(verification.Failed(), ", "))
+	}
+	return nil
+}
+
+func faucetMain(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a rollup name and an address, got %d arguments", len(args))
+	}
+	drip, err := faucetFlags.client().Faucet(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	if *faucetFlags.output == "json" {
+		return printJSON(drip)
+	}
+	fmt.Printf("sent %s wei to %s in %s\n", drip.AmountWei, drip.Address, drip.TxHash)
+	return nil
+}
+
+func walletsMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	if len(walletsFundFlag) > 0 {
+		transfers, err := walletsFlags.client().FundWallets(ctx, name, walletsFundFlag)
+		if err != nil {
+			return err
+		}
+		if *walletsFlags.output == "json" {
+			return printJSON(transfers)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ADDRESS\tAMOUNT\tTX")
+		for _, t := range transfers {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Address, t.AmountWei, t.TxHash)
+		}
+		return w.Flush()
+	}
+	balances, err := walletsFlags.client().ListWallets(ctx, name)
+	if err != nil {
+		return err
+	}
+	if *walletsFlags.output == "json" {
+		return printJSON(balances)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tGENESIS\tTRANSFERS\tBALANCE")
+	for _, b := range balances {
+		genesis := b.AmountInWei
+		if genesis == "" {
+			genesis = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", b.WalletAddress, genesis, len(b.Transfers), b.BalanceWei)
+	}
+	return w.Flush()
+}
+
+func chainMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	metadata, err := chainFlags.client().ChainMetadata(ctx, name)
+	if err != nil {
+		return err
+	}
+	switch *chainDocFlag {
+	case "":
+		return printJSON(metadata)
+	case "wallet":
+		return printJSON(metadata.Wallet)
+	case "chain-list":
+		return printJSON(metadata.ChainList)
+	default:
+		return fmt.Errorf("--doc must be wallet or chain-list, got %q", *chainDocFlag)
+	}
+}
+
+func contractsMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	registry, err := contractsFlags.client().Contracts(ctx, name)
+	if err != nil {
+		return err
+	}
+	if *contractsFlags.output == "json" {
+		return printJSON(registry)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LAYER\tNAME\tADDRESS\tIMPLEMENTATION\tVERSION\tCODE")
+	for _, c := range registry.Contracts {
+		implementation := c.Implementation
+		if implementation == "" {
+			implementation = "-"
+		}
+		code := c.Code
+		if c.CodeDetail != "" {
+			code += " (" + c.CodeDetail + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", c.Layer, c.Name, c.Address, implementation, c.Version, code)
+	}
+	return w.Flush()
+}
+
+func logsMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	logs, err := logsFlags.client().GetRollupLogs(ctx, name, *logsServiceFlag, *logsTailFlag)
+	if err != nil {
+		return err
+	}
+	if *logsFlags.output == "json" {
+		return printJSON(logs)
+	}
+	fmt.Print(logs)
+	return nil
+}
+
+func statusMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	if *statusWaitFlag {
+		return waitRollup(ctx, statusFlags, name)
+	}
+	job, err := statusFlags.client().GetRollupStatus(ctx, name)
+	if err != nil {
+		return err
+	}
+	return printJob(statusFlags, job)
+}
+
+func proverMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	status, err := proverFlags.client().GetProverStatus(ctx, name)
+	if err != nil {
+		return err
+	}
+	if *proverFlags.output == "json" {
+		return printJSON(status)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tMODE\tDEPLOYMENT\tONLINE\tPENDING\tFAILED\tL2 HEAD\tPROVEN\tLAG\tERROR")
+	fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\t%d\t%d\t%d\t%d\t%s\n", status.Name, status.Mode, status.Deployment,
+		status.Online, status.PendingTasks, status.FailedTasks, status.L2Head, status.LatestProvenBlock, status.ProofLag, status.Error)
+	return w.Flush()
+}
+
+func upgradeMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := upgradeFlags.client().UpgradeContracts(ctx, name, *upgradeVersionFlag)
+	if err != nil {
+		return err
+	}
+	if *upgradeFlags.output == "json" {
+		return printJSON(rollup)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FROM\tTO\tAT\tERROR")
+	for _, u := range rollup.Upgrades {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", u.From, u.To, u.At.Format(time.RFC3339), u.Error)
+	}
+	return w.Flush()
+}
+
+func upgradeImageMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := upgradeImageFlags.client().UpgradeImage(ctx, name, *upgradeImageFlag, *upgradeImageVersionFlag)
+	if err != nil {
+		return err
+	}
+	if *upgradeImageFlags.output == "json" {
+		return printJSON(rollup)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "IMAGE\tTAG\tDIGEST")
+	fmt.Fprintf(w, "execution\t%s\t%s\n", rollup.ExecutionImage, rollup.ExecutionDigest)
+	fmt.Fprintf(w, "consensus\t%s\t%s\n", rollup.ConsensusImage, rollup.ConsensusDigest)
+	return w.Flush()
+}
+
+func scaleMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := scaleFlags.client().ScaleReplicas(ctx, name, *scaleReplicasFlag)
+	if err != nil {
+		return err
+	}
+	if *scaleFlags.output == "json" {
+		return printJSON(rollup)
+	}
+	return printRollups(scaleFlags, []*types.Rollup{rollup})
+}
+
+func rotateJwtMain(ctx context.Context, args []string) error {
+	name, err := nameArg(args)
+	if err != nil {
+		return err
+	}
+	rollup, err := rotateJwtFlags.client().RotateJwtSecret(ctx, name)
+	if err != nil {
+		return err
+	}
+	if *rotateJwtFlags.output == "json" {
+		return printJSON(rollup)
+	}
+	fmt.Printf("rotated the jwt secret of rollup %s at %s\n", name, rollup.JwtRotatedAt.Format(time.RFC3339))
+	return nil
+}
+
+// waitRollup polls the provisioning job and prints every change until the
+// rollup is online or provisioning failed.
+func waitRollup(ctx context.Context, flags *cliFlags, name string) error {
+	c := flags.client()
+	var last *types.RollupJob
+	for {
+		job, err := c.GetRollupStatus(ctx, name)
+		if err != nil {
+			return err
+		}
+		if last == nil || *last != *job {
+			if err := printJob(flags, job); err != nil {
+				return err
+			}
+			last = job
+		}
+		if job.Error != "" {
+			return fmt.Errorf("rollup %s failed: %s", name, job.Error)
+		}
+		if job.Step == types.Online {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * 5):
+		}
+	}
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printJob(flags *cliFlags, job *types.RollupJob) error {
+	if *flags.output == "json" {
+		return printJSON(job)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tstep %d\t%s\t%s\n", job.Name, job.Step, job.Status, job.Error)
+	return w.Flush()
+}
+
+func printRollups(flags *cliFlags, rollups []*types.Rollup) error {
+	if *flags.output == "json" {
+		return printJSON(rollups)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCHAIN ID\tSTEP\tSTATUS\tRPC\tL1 ROLLUP")
+	for _, r := range rollups {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", r.Name, r.ChainId, r.Step, r.Status, r.RpcUrl, r.L1Rollup)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/g1g2-lab/automation/l2"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+var (
+	renderFlagSet    = flag.NewFlagSet("g1g2 rollup render", flag.ExitOnError)
+	renderFileFlag   = renderFlagSet.String("f", "", "rollup spec file (yaml or json)")
+	renderOutFlag    = renderFlagSet.String("o", "", "dir the artifacts are rendered into")
+	renderConfigFlag = renderFlagSet.String("config", "rollup_server_prod.yaml", "g1g2 configuration file")
+	renderFormatFlag = renderFlagSet.String("output", "table", "plan format: table or json")
+	renderDbFlag     = renderFlagSet.String("db", "build/db", "rollup record dir, shared with the rollup server")
+	renderCommand    = &ffcli.Command{
+		Name:       "render",
+		ShortUsage: "g1g2 rollup render -f spec.yaml -o dir",
+		ShortHelp:  "render all artifacts of a spec and print the plan, without deploying",
+		FlagSet:    renderFlagSet,
+		Exec:       renderMain,
+	}
+)
+
+func renderMain(ctx context.Context, args []string) error {
+	if *renderFileFlag == "" || *renderOutFlag == "" {
+		return fmt.Errorf("-f and -o are required")
+	}
+	spec, err := types.NewRollupSpecFromFile(*renderFileFlag)
+	if err != nil {
+		return err
+	}
+	l2Config, err := l2.NewL2ConfigFromFile(*renderConfigFlag)
+	if err != nil {
+		return err
+	}
+	db := db.NewLocalDatabase(ctx, *renderDbFlag)
+	plan, err := l2.PlanRollup(ctx, l2Config, spec, db, *renderOutFlag)
+	if err != nil {
+		return err
+	}
+	if *renderFormatFlag == "json" {
+		return printJSON(plan)
+	}
+	return printPlan(plan)
+}
+
+func printPlan(plan *types.RollupPlan) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "rollup %s (chain id %d) on l1 %s\n", plan.Rollup.Name, plan.Rollup.ChainId, plan.Rollup.L1.Name)
+	fmt.Fprintf(w, "artifacts in %s:\n", plan.OutDir)
+	for _, a := range plan.Artifacts {
+		fmt.Fprintf(w, "  %s\n", a)
+	}
+	fmt.Fprintln(w, "\ncontracts:")
+	for _, c := range plan.Contracts {
+		action := "deploy"
+		if c.Deployed {
+			action = "reuse"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", action, c.Layer, c.Name, c.Address)
+	}
+	fmt.Fprintln(w, "\nimages:")
+	for _, i := range plan.Images {
+		action := "pull"
+		if i.Build {
+			action = "build"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", action, i.Service, i.Image)
+	}
+	fmt.Fprintln(w, "\nports:")
+	for _, p := range plan.Ports {
+		fmt.Fprintf(w, "  %s\t%s -> %s\n", p.Service, p.HostPort, p.ContainerPort)
+	}
+	return w.Flush()
+}
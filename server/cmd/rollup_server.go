@@ -4,8 +4,10 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 
+	"github.com/g1g2-lab/automation/api"
 	"github.com/g1g2-lab/automation/l2"
 	"github.com/g1g2-lab/automation/pkg/db"
 	"github.com/g1g2-lab/automation/pkg/http"
@@ -31,6 +33,31 @@ var (
 		},
 		Subcommands: []*ffcli.Command{
 			serverCommand,
+			createCommand,
+			listCommand,
+			getCommand,
+			deleteCommand,
+			stopCommand,
+			startCommand,
+			archiveCommand,
+			forkCommand,
+			backupCommand,
+			backupsCommand,
+			restoreCommand,
+			verifyCommand,
+			faucetCommand,
+			walletsCommand,
+			chainCommand,
+			contractsCommand,
+			logsCommand,
+			statusCommand,
+			proverCommand,
+			scaleCommand,
+			rotateJwtCommand,
+			upgradeCommand,
+			upgradeImageCommand,
+			applyCommand,
+			renderCommand,
 		},
 	}
 )
@@ -59,6 +86,10 @@ func serverMain(ctx context.Context, args []string) error {
 	// Echo instance
 	e := echo.New()
 	e.Validator = &http.CustomValidator{Validator: validator.New()}
+	e.IPExtractor, err = ipExtractor(l2Config.ServerConfig.TrustedProxies)
+	if err != nil {
+		return err
+	}
 	//
 	//// Middleware
 	e.Use(middleware.Logger())
@@ -75,8 +106,32 @@ func serverMain(ctx context.Context, args []string) error {
 
 	handler := server.NewRollupHandler(db, l2Config)
 	handler.SetupRollupRouter(e, db)
+	if err := api.CheckRoutes(e.Routes()); err != nil {
+		return err
+	}
 
 	// Start server
 	e.Logger.Fatal(e.Start(fmt.Sprintf(":%d", *portFlag)))
 	return nil
 }
+
+// ipExtractor returns the client ip of a request, the faucet limits requests
+// per client ip. Headers naming it are only believed from trusted proxies.
+func ipExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server trusted_proxies cidr %q, %w", cidr, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -4,22 +4,41 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"math/big"
 	"os"
 	"os/signal"
-	"sync"
+	"sort"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
-	"github.com/g1g2-lab/automation/util"
+	"github.com/g1g2-lab/automation/client"
+	"github.com/g1g2-lab/automation/pkg/env"
+	"github.com/g1g2-lab/automation/pkg/loadgen"
 	"github.com/peterbourgon/ff/v3/ffcli"
 )
 
 var (
-	genTxsFlagSet = flag.NewFlagSet("gen-txs", flag.ExitOnError)
-	genTxsCommand = &ffcli.Command{
+	defaultFunderKey = env.Get("G1G2_FUNDER_KEY", "", "private key funding the accounts of gen-txs")
+
+	genTxsFlagSet      = flag.NewFlagSet("gen-txs", flag.ExitOnError)
+	genTxsRollupFlag   = genTxsFlagSet.String("rollup", "", "rollup to load, its rpc url is looked up on the rollup server")
+	genTxsServerFlag   = genTxsFlagSet.String("server", defaultServerUrl, "rollup server url (env G1G2_SERVER_URL)")
+	genTxsRpcFlag      = genTxsFlagSet.String("rpc", "http://127.0.0.1:11545", "l2 rpc url, used when --rollup is not set")
+	genTxsFunderFlag   = genTxsFlagSet.String("funder-key", defaultFunderKey, "private key funding the accounts (env G1G2_FUNDER_KEY)")
+	genTxsAccountsFlag = genTxsFlagSet.Int("accounts", 10, "number of generated sender accounts")
+	genTxsFundFlag     = genTxsFlagSet.String("fund", "10000000000000000", "wei sent to each generated account")
+	genTxsTpsFlag      = genTxsFlagSet.Float64("tps", 10, "target transactions per second")
+	genTxsWorkersFlag  = genTxsFlagSet.Int("concurrency", 4, "concurrent senders, at most one per account")
+	genTxsDurationFlag = genTxsFlagSet.Duration("duration", time.Minute, "how long to send transactions")
+	genTxsDrainFlag    = genTxsFlagSet.Duration("drain", 30*time.Second, "how long to wait for submitted transactions after the duration")
+	genTxsMixFlag      = genTxsFlagSet.String("mix", "transfer=70,erc20=20,deploy=5,storage=5", "weights of the transaction kinds: transfer, deploy, erc20, storage")
+	genTxsSlotsFlag    = genTxsFlagSet.Int("storage-slots", 20, "fresh storage slots written by a storage call")
+	genTxsOutputFlag   = genTxsFlagSet.String("output", "table", "output format of the report: table or json")
+	genTxsCommand      = &ffcli.Command{
 		Name:       "gen-txs",
-		ShortUsage: "",
-		ShortHelp:  "🌟gen transactions for testing",
+		ShortUsage: "g1g2 gen-txs [flags]",
+		ShortHelp:  "🌟send a transaction load to a rollup and report inclusion",
 		LongHelp:   "",
 
 		FlagSet: genTxsFlagSet,
@@ -28,20 +47,53 @@ var (
 )
 
 func genTxsMain(ctx context.Context, args []string) error {
-	println("Gen transations start.")
-	genTxsService, err := NewGenTransactionsService(ctx)
+	rpcUrl := *genTxsRpcFlag
+	if *genTxsRollupFlag != "" {
+		rollup, err := client.New(*genTxsServerFlag).GetRollup(ctx, *genTxsRollupFlag)
+		if err != nil {
+			return err
+		}
+		rpcUrl = rollup.RpcUrl
+	}
+	fund, ok := new(big.Int).SetString(*genTxsFundFlag, 10)
+	if !ok {
+		return fmt.Errorf("--fund must be an amount in wei, got %q", *genTxsFundFlag)
+	}
+	mix, err := loadgen.ParseMix(*genTxsMixFlag)
 	if err != nil {
 		return err
 	}
-	genTxsService.Start()
-	defer func() {
-		genTxsService.Stop()
-	}()
 
-	quitCh := getQuitCh()
-	<-quitCh
-
-	return nil
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// stop early on a signal and still print the report
+		<-getQuitCh()
+		cancel()
+	}()
+	gen, err := loadgen.New(ctx, loadgen.Config{
+		RpcUrl:       rpcUrl,
+		FunderKey:    *genTxsFunderFlag,
+		Accounts:     *genTxsAccountsFlag,
+		Tps:          *genTxsTpsFlag,
+		Concurrency:  *genTxsWorkersFlag,
+		Duration:     *genTxsDurationFlag,
+		Drain:        *genTxsDrainFlag,
+		Fund:         fund,
+		Mix:          mix,
+		StorageSlots: *genTxsSlotsFlag,
+	})
+	if err != nil {
+		return err
+	}
+	report, err := gen.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if *genTxsOutputFlag == "json" {
+		return printJSON(report)
+	}
+	return printLoadReport(report)
 }
 
 func getQuitCh() chan os.Signal {
@@ -55,81 +107,33 @@ func getQuitCh() chan os.Signal {
 	return quitCh
 }
 
-type GenTransactionsService struct {
-	wg            sync.WaitGroup
-	genTxInterval time.Duration
-	ctx           context.Context
-	cancel        context.CancelFunc
-}
-
-func NewGenTransactionsService(ctx context.Context) (*GenTransactionsService, error) {
-	context, cancel := context.WithCancel(ctx)
-	interval := time.Second * 2
-
-	return &GenTransactionsService{
-		wg:            sync.WaitGroup{},
-		genTxInterval: interval,
-		ctx:           context,
-		cancel:        cancel,
-	}, nil
-}
-
-func (s *GenTransactionsService) Start() {
-	s.wg.Add(1)
-	go s.loopGenTx()
-}
-
-func (s *GenTransactionsService) Stop() {
-	s.cancel()
-	s.wg.Wait()
-}
-
-func (s *GenTransactionsService) loopGenTx() {
-	ticker := time.NewTicker(s.genTxInterval)
-	defer func() {
-		ticker.Stop()
-		s.wg.Done()
-	}()
-
-	for {
-		select {
-		case <-s.ctx.Done():
-			println("loopGenTx return by ctx.Done()")
-			return
-		case <-ticker.C:
-			if err := s.genL2Tx(); err != nil {
-				println("Failed to gen tx", "error", err.Error())
-			}
-		}
+func printLoadReport(r *loadgen.Report) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tSUBMITTED\tINCLUDED\tFAILED")
+	kinds := []string{}
+	for kind := range r.Kinds {
+		kinds = append(kinds, kind)
 	}
-}
-
-var (
-	newPk  = ""
-	newAdd = ""
-)
-
-func (s *GenTransactionsService) genL2Tx() error {
-	if len(newPk) == 0 {
-		newPk, newAdd = util.CreateAccount()
-		_, err := util.ExecWrapper(
-			fmt.Sprintf("cast send %s --value=10000000000000000 --private-key=dc4cf1bba6e4f415e5da84faa4d9981120f8cd1c8a5c956480aeae369325a59f --rpc-url=http://127.0.0.1:11545 --legacy", newAdd),
-		).String()
-		if err != nil {
-			newPk = ""
-			newAdd = ""
-			println(err.Error())
-		}
-	} else {
-		_, err := util.ExecWrapper(
-			fmt.Sprintf("cast send %s --value=1 --private-key=%s --rpc-url=http://127.0.0.1:11545 --legacy", newAdd, newPk),
-		).String()
-		if err != nil {
-			newPk = ""
-			newAdd = ""
-			println(err.Error())
-		}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		k := r.Kinds[kind]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", kind, k.Submitted, k.Included, k.Failed)
+	}
+	fmt.Fprintf(w, "total\t%d\t%d\t%d\n", r.Submitted, r.Included, r.Failed)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "rpc\t%s\n", r.RpcUrl)
+	fmt.Fprintf(w, "duration\t%s\n", r.End.Sub(r.Start).Round(time.Millisecond))
+	fmt.Fprintf(w, "pending\t%d\n", r.Pending)
+	fmt.Fprintf(w, "skipped\t%d\n", r.Skipped)
+	fmt.Fprintf(w, "latency p50/p90/p99\t%s / %s / %s\n", r.LatencyP50.Round(time.Millisecond),
+		r.LatencyP90.Round(time.Millisecond), r.LatencyP99.Round(time.Millisecond))
+	fmt.Fprintf(w, "tps target/achieved\t%.2f / %.2f\n", r.TargetTps, r.AchievedTps)
+	for msg, count := range r.Errors {
+		fmt.Fprintf(w, "error\t%dx %s\n", count, msg)
 	}
-	println("generate transaction to l2 success")
-	return nil
+	return w.Flush()
 }
@@ -56,7 +56,7 @@ require (
 )
 
 require (
-	bitbucket.org/creachadair/shell v0.0.7 // indirect
+	bitbucket.org/creachadair/shell v0.0.7
 	github.com/ethereum/go-ethereum v1.10.26
 	github.com/fatih/color v1.13.0
 	github.com/inconshreveable/log15 v0.0.0-20201112154412-8562bdadbbac
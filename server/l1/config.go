@@ -0,0 +1,159 @@
+package l1
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/g1g2-lab/automation/types"
+)
+
+// Dir is where the dev chain is rendered and the network registry kept.
+const Dir = "build/l1"
+
+// DevConfig describes the local chain of g1g2 l1. Empty fields take the
+// defaults of the docker dev l1.
+type DevConfig struct {
+	Name string `yaml:"name"`
+	// Provider runs the chain: docker for clique geth in docker, anvil or
+	// geth-dev for a local process.
+	Provider string `yaml:"provider"`
+	ChainId  int    `yaml:"chain_id"`
+	// Period is the seconds between blocks.
+	Period   int    `yaml:"period"`
+	GasLimit uint64 `yaml:"gas_limit"`
+	// MinerKey seals the blocks, it defaults to the admin key of the config
+	// so the admin deploying the rollup contracts is funded.
+	MinerKey        string `yaml:"miner_key"`
+	MinerPremintWei string `yaml:"miner_premint_wei"`
+	// Accounts are prefunded in the genesis, address to wei.
+	Accounts  map[string]string `yaml:"accounts"`
+	GethImage string            `yaml:"geth_image"`
+	// HttpPort is the host port of the http rpc, ws is HttpPort+1 and the
+	// engine api HttpPort+2.
+	HttpPort     int  `yaml:"http_port"`
+	Explorer     bool `yaml:"explorer"`
+	ExplorerPort int  `yaml:"explorer_port"`
+}
+
+var DefaultDevConfig = DevConfig{
+	Name:            types.G1G2DockerDevL1.Name,
+	Provider:        ProviderDocker,
+	ChainId:         types.G1G2DockerDevL1.ChainId,
+	Period:          2,
+	GasLimit:        15000000,
+	MinerPremintWei: "1000000000000000000000000000",
+	GethImage:       "ethereum/client-go:v1.10.25",
+	HttpPort:        10545,
+	ExplorerPort:    4001,
+}
+
+// WithDefaults fills the empty fields of c from DefaultDevConfig, with
+// adminKey as the miner key.
+func (c DevConfig) WithDefaults(adminKey string) DevConfig {
+	if c.MinerKey == "" {
+		c.MinerKey = adminKey
+	}
+	if c.Provider == ProviderGethDev && c.ChainId == 0 {
+		c.ChainId = gethDevChainId
+	}
+	return c.Merge(DefaultDevConfig)
+}
+
+// Merge fills the empty fields of c from defaults.
+func (c DevConfig) Merge(defaults DevConfig) DevConfig {
+	if c.Name == "" {
+		c.Name = defaults.Name
+	}
+	if c.Provider == "" {
+		c.Provider = defaults.Provider
+	}
+	if c.ChainId == 0 {
+		c.ChainId = defaults.ChainId
+	}
+	if c.Period == 0 {
+		c.Period = defaults.Period
+	}
+	if c.GasLimit == 0 {
+		c.GasLimit = defaults.GasLimit
+	}
+	if c.MinerKey == "" {
+		c.MinerKey = defaults.MinerKey
+	}
+	if c.MinerPremintWei == "" {
+		c.MinerPremintWei = defaults.MinerPremintWei
+	}
+	if c.GethImage == "" {
+		c.GethImage = defaults.GethImage
+	}
+	if c.HttpPort == 0 {
+		c.HttpPort = defaults.HttpPort
+	}
+	if c.ExplorerPort == 0 {
+		c.ExplorerPort = defaults.ExplorerPort
+	}
+	return c
+}
+
+func (c *DevConfig) Validate() error {
+	switch c.Provider {
+	case ProviderDocker, ProviderAnvil:
+	case ProviderGethDev:
+		// geth --dev always runs chain id 1337
+		if c.ChainId != gethDevChainId {
+			return fmt.Errorf("l1 provider geth-dev runs chain id %d, got %d", gethDevChainId, c.ChainId)
+		}
+	default:
+		return fmt.Errorf("l1 provider must be docker, anvil or geth-dev, got %q", c.Provider)
+	}
+	if c.ChainId <= 0 {
+		return fmt.Errorf("l1 chain_id must be positive, got %d", c.ChainId)
+	}
+	if c.Period <= 0 {
+		return fmt.Errorf("l1 period must be positive, got %d", c.Period)
+	}
+	if c.MinerKey == "" {
+		return fmt.Errorf("l1 miner_key or g1g2_admin.l1_admin_pk is required")
+	}
+	if _, err := minerAddress(c.MinerKey); err != nil {
+		return err
+	}
+	amounts := map[string]string{"miner": c.MinerPremintWei}
+	for address, amount := range c.Accounts {
+		if !common.IsHexAddress(address) {
+			return fmt.Errorf("invalid l1 account %q", address)
+		}
+		amounts[address] = amount
+	}
+	for account, amount := range amounts {
+		if v, ok := new(big.Int).SetString(amount, 10); !ok || v.Sign() < 0 {
+			return fmt.Errorf("balance of l1 account %s must be an amount in wei, got %q", account, amount)
+		}
+	}
+	if c.HttpPort <= 0 || c.HttpPort+2 > 65535 || c.ExplorerPort <= 0 || c.ExplorerPort > 65535 {
+		return fmt.Errorf("l1 ports must be between 1 and 65535")
+	}
+	return nil
+}
+
+// Network is the entry of the chain in the network registry.
+func (c *DevConfig) Network() types.L1Net {
+	wsPort := c.HttpPort + 1
+	if c.Provider == ProviderAnvil {
+		// anvil serves ws on its http port
+		wsPort = c.HttpPort
+	}
+	net := types.L1Net{
+		Name:           c.Name,
+		ChainId:        c.ChainId,
+		PublicRpcUrl:   fmt.Sprintf("http://127.0.0.1:%d", c.HttpPort),
+		PublicWsUrl:    fmt.Sprintf("ws://127.0.0.1:%d", wsPort),
+		InternalRpcUrl: fmt.Sprintf("http://host.docker.internal:%d", c.HttpPort),
+		InternalWsUrl:  fmt.Sprintf("ws://host.docker.internal:%d", wsPort),
+		Provider:       c.Provider,
+	}
+	if c.Explorer && c.Provider == ProviderDocker {
+		net.ExplorerUrl = fmt.Sprintf("http://localhost:%d", c.ExplorerPort)
+	}
+	return net
+}
@@ -0,0 +1,252 @@
+package l1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/templates"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+const (
+	// composeProject keeps the containers and volumes of the l1 started by
+	// hand from the former l1_geth dir.
+	composeProject = "l1_geth"
+	genesisFile    = "genesis.json"
+	readyTimeout   = time.Minute * 3
+)
+
+// Status reports the dev chain as seen from its rpc.
+type Status struct {
+	Network types.L1Net `json:"network"`
+	Running bool        `json:"running"`
+	Head    uint64      `json:"head"`
+	HeadAge string      `json:"head_age,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func devDir() string {
+	return util.ToAbsolutePath(Dir)
+}
+
+func compose(command string) error {
+	dockerCompose := path.Join(devDir(), "docker-compose.yaml")
+	_, err := util.ExecWrapper(fmt.Sprintf("docker compose -p %s -f %s %s", composeProject, dockerCompose, command)).Stdout()
+	return err
+}
+
+// dockerProvider runs the dev l1 as clique geth in docker, with an optional
+// blockscout explorer. Its miner funds the accounts.
+type dockerProvider struct {
+	cfg         *DevConfig
+	overrideDir string
+}
+
+// Up renders the dev chain of cfg and starts it, waits until blocks are
+// sealed and registers the chain. The genesis of the docker chain is
+// generated once, a chain already created with another chain id or period
+// has to be reset.
+func Up(ctx context.Context, cfg *DevConfig, overrideDir string) (*types.L1Net, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(devDir(), 0755); err != nil {
+		return nil, err
+	}
+	chain := newDevChain(cfg, overrideDir)
+	if err := chain.up(ctx); err != nil {
+		return nil, err
+	}
+	net := chain.Network()
+	if err := RegisterNetwork(net); err != nil {
+		return nil, err
+	}
+	return &net, nil
+}
+
+func (p *dockerProvider) Network() types.L1Net {
+	return p.cfg.Network()
+}
+
+func (p *dockerProvider) Start(ctx context.Context) error {
+	if NetworkStatus(ctx, p.Network()).Running {
+		return nil
+	}
+	if err := p.cfg.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(devDir(), 0755); err != nil {
+		return err
+	}
+	return p.up(ctx)
+}
+
+func (p *dockerProvider) up(ctx context.Context) error {
+	cfg := p.cfg
+	if err := writeGenesis(cfg); err != nil {
+		return err
+	}
+	miner, err := minerAddress(cfg.MinerKey)
+	if err != nil {
+		return err
+	}
+	data := &templates.L1GethTemplateData{
+		GethImage:    cfg.GethImage,
+		ChainId:      cfg.ChainId,
+		MinerKey:     cfg.MinerKey,
+		MinerAddress: miner.Hex(),
+		HttpPort:     cfg.HttpPort,
+		WsPort:       cfg.HttpPort + 1,
+		AuthPort:     cfg.HttpPort + 2,
+		Explorer:     cfg.Explorer,
+		ExplorerPort: cfg.ExplorerPort,
+	}
+	renderer := templates.NewRenderer(p.overrideDir)
+	for _, name := range []string{
+		"l1_geth/Dockerfile_tmpl",
+		"l1_geth/init_l1_geth_tmpl.sh",
+		"l1_geth/docker-compose_tmpl.yaml",
+	} {
+		if err := renderer.Render(name, devDir(), data); err != nil {
+			return err
+		}
+	}
+
+	util.PrintStepLogo("START L1")
+	if err := compose("up -d --build --remove-orphans"); err != nil {
+		return err
+	}
+	return waitReady(ctx, p.Network())
+}
+
+func (p *dockerProvider) Stop() error {
+	return compose("down")
+}
+
+func (p *dockerProvider) reset() error {
+	if _, err := os.Stat(path.Join(devDir(), "docker-compose.yaml")); err == nil {
+		if err := compose("down -v"); err != nil {
+			return err
+		}
+	}
+	err := os.Remove(path.Join(devDir(), genesisFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (p *dockerProvider) ChainId(ctx context.Context) (*big.Int, error) {
+	return chainId(ctx, p.Network())
+}
+
+func (p *dockerProvider) Fund(ctx context.Context, address common.Address, amount *big.Int) error {
+	return transfer(ctx, p.Network(), p.cfg.MinerKey, address, amount)
+}
+
+// Mine waits for the blocks, clique seals one every period.
+func (p *dockerProvider) Mine(ctx context.Context, blocks int) error {
+	return waitBlocks(ctx, p.Network(), blocks)
+}
+
+// writeGenesis generates the genesis unless the chain has one, which must
+// then match the chain id and period of cfg.
+func writeGenesis(cfg *DevConfig) error {
+	file := path.Join(devDir(), genesisFile)
+	existing := &core.Genesis{}
+	err := util.ReadJSONTo(existing, file)
+	switch {
+	case err == nil:
+		if existing.Config.ChainID.Int64() != int64(cfg.ChainId) ||
+			existing.Config.Clique == nil || existing.Config.Clique.Period != uint64(cfg.Period) {
+			return fmt.Errorf("the l1 chain was created with chain id %d and another period, reset it to change its genesis", existing.Config.ChainID)
+		}
+		return nil
+	case !os.IsNotExist(err):
+		return err
+	}
+	genesis, err := cfg.Genesis()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0644)
+}
+
+// waitReady waits for the rpc to serve the chain id of net and a sealed
+// block.
+func waitReady(ctx context.Context, net types.L1Net) error {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+	for {
+		status := NetworkStatus(ctx, net)
+		if status.Running && status.Head > 0 {
+			log15.Info("L1 is running...", "rpc", net.PublicRpcUrl, "head", status.Head)
+			return nil
+		}
+		log15.Info("waiting for l1", "rpc", net.PublicRpcUrl, "err", status.Error)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("l1 at %s is not ready after %s, %s", net.PublicRpcUrl, readyTimeout, status.Error)
+		case <-time.After(time.Second * 2):
+		}
+	}
+}
+
+// Down stops the dev chain of cfg and keeps its data.
+func Down(cfg *DevConfig, overrideDir string) error {
+	return newDevChain(cfg, overrideDir).Stop()
+}
+
+// Reset removes the dev chain of cfg with its data and starts a new one.
+// The contracts of every rollup deployed on it are gone.
+func Reset(ctx context.Context, cfg *DevConfig, overrideDir string) (*types.L1Net, error) {
+	if err := newDevChain(cfg, overrideDir).reset(); err != nil {
+		return nil, err
+	}
+	return Up(ctx, cfg, overrideDir)
+}
+
+// NetworkStatus asks the rpc of net for its chain id and head.
+func NetworkStatus(ctx context.Context, net types.L1Net) *Status {
+	status := &Status{Network: net}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, net.PublicRpcUrl)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer client.Close()
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if chainId.Int64() != int64(net.ChainId) {
+		status.Error = fmt.Sprintf("rpc serves chain id %s, expected %d", chainId, net.ChainId)
+		return status
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Running = true
+	status.Head = head.Number.Uint64()
+	status.HeadAge = time.Since(time.Unix(int64(head.Time), 0)).Round(time.Second).String()
+	return status
+}
@@ -0,0 +1,45 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/g1g2-lab/automation/types"
+)
+
+// externalProvider reaches an l1 g1g2 does not run, such as a testnet. It
+// funds accounts from the funder key and cannot seal blocks on demand.
+type externalProvider struct {
+	net       types.L1Net
+	funderKey string
+}
+
+func (p *externalProvider) Network() types.L1Net {
+	return p.net
+}
+
+func (p *externalProvider) Start(ctx context.Context) error {
+	status := NetworkStatus(ctx, p.net)
+	if !status.Running {
+		return fmt.Errorf("l1 %s at %s is not reachable, %s", p.net.Name, p.net.PublicRpcUrl, status.Error)
+	}
+	return nil
+}
+
+func (p *externalProvider) Stop() error {
+	return fmt.Errorf("l1 %s is external, g1g2 does not run it", p.net.Name)
+}
+
+func (p *externalProvider) ChainId(ctx context.Context) (*big.Int, error) {
+	return chainId(ctx, p.net)
+}
+
+func (p *externalProvider) Fund(ctx context.Context, address common.Address, amount *big.Int) error {
+	return transfer(ctx, p.net, p.funderKey, address, amount)
+}
+
+func (p *externalProvider) Mine(ctx context.Context, blocks int) error {
+	return waitBlocks(ctx, p.net, blocks)
+}
@@ -0,0 +1,67 @@
+package l1
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func minerAddress(key string) (common.Address, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid l1 miner key, %w", err)
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey), nil
+}
+
+// Genesis builds the clique genesis of the chain, sealed by the miner alone.
+func (c *DevConfig) Genesis() (*core.Genesis, error) {
+	miner, err := minerAddress(c.MinerKey)
+	if err != nil {
+		return nil, err
+	}
+	alloc := core.GenesisAlloc{}
+	for address, amount := range c.Accounts {
+		balance, _ := new(big.Int).SetString(amount, 10)
+		alloc[common.HexToAddress(address)] = core.GenesisAccount{Balance: balance}
+	}
+	premint, _ := new(big.Int).SetString(c.MinerPremintWei, 10)
+	alloc[miner] = core.GenesisAccount{Balance: premint}
+
+	// clique keeps 32 vanity bytes, the signers and a 65 byte seal
+	extra := make([]byte, 32, 32+common.AddressLength+crypto.SignatureLength)
+	extra = append(extra, miner.Bytes()...)
+	extra = append(extra, make([]byte, crypto.SignatureLength)...)
+	zero := big.NewInt(0)
+	return &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:             big.NewInt(int64(c.ChainId)),
+			HomesteadBlock:      zero,
+			EIP150Block:         zero,
+			EIP155Block:         zero,
+			EIP158Block:         zero,
+			ByzantiumBlock:      zero,
+			ConstantinopleBlock: zero,
+			PetersburgBlock:     zero,
+			IstanbulBlock:       zero,
+			MuirGlacierBlock:    zero,
+			BerlinBlock:         zero,
+			LondonBlock:         zero,
+			Clique: &params.CliqueConfig{
+				Period: uint64(c.Period),
+				Epoch:  30000,
+			},
+		},
+		Timestamp:  uint64(time.Now().Unix()),
+		ExtraData:  extra,
+		GasLimit:   c.GasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}, nil
+}
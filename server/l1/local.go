@@ -0,0 +1,233 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+// localProvider runs the l1 as a local anvil or geth --dev process, lighter
+// than the docker chain for ci. Its state is kept in its dir under Dir.
+type localProvider struct {
+	cfg *DevConfig
+	net types.L1Net
+}
+
+func (p *localProvider) Network() types.L1Net {
+	return p.net
+}
+
+func (p *localProvider) dir() string {
+	return path.Join(devDir(), p.cfg.Name)
+}
+
+func (p *localProvider) pidFile() string {
+	return path.Join(p.dir(), "pid")
+}
+
+// command returns the process of the chain, sealing a block every period.
+func (p *localProvider) command() (string, []string) {
+	cfg := p.cfg
+	if cfg.Provider == ProviderAnvil {
+		return "anvil", []string{
+			"--host", "0.0.0.0",
+			"--port", strconv.Itoa(cfg.HttpPort),
+			"--chain-id", strconv.Itoa(cfg.ChainId),
+			"--block-time", strconv.Itoa(cfg.Period),
+			"--gas-limit", strconv.FormatUint(cfg.GasLimit, 10),
+			"--state", path.Join(p.dir(), "state.json"),
+		}
+	}
+	return "geth", []string{
+		"--dev",
+		"--dev.period", strconv.Itoa(cfg.Period),
+		"--datadir", path.Join(p.dir(), "geth"),
+		"--miner.gaslimit", strconv.FormatUint(cfg.GasLimit, 10),
+		"--http", "--http.addr", "0.0.0.0", "--http.port", strconv.Itoa(cfg.HttpPort),
+		"--http.api", "eth,net,web3,debug,txpool", "--http.corsdomain", "*", "--http.vhosts", "*",
+		"--ws", "--ws.addr", "0.0.0.0", "--ws.port", strconv.Itoa(cfg.HttpPort + 1),
+		"--ws.api", "eth,net,web3,debug,txpool", "--ws.origins", "*",
+		"--authrpc.port", strconv.Itoa(cfg.HttpPort + 2),
+		"--port", "0", "--nodiscover",
+	}
+}
+
+func (p *localProvider) Start(ctx context.Context) error {
+	return p.up(ctx)
+}
+
+// up spawns the process unless it serves blocks already. A new chain funds
+// the miner and the accounts of the config, there is no genesis to premint
+// them.
+func (p *localProvider) up(ctx context.Context) error {
+	if NetworkStatus(ctx, p.net).Running {
+		return nil
+	}
+	name, args := p.command()
+	bin, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("l1 provider %s needs %s on the path, %w", p.cfg.Provider, name, err)
+	}
+	_, err = os.Stat(p.dir())
+	fresh := os.IsNotExist(err)
+	if err := os.MkdirAll(p.dir(), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(path.Join(p.dir(), name+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	util.PrintStepLogo("START L1")
+	util.PrintCmdMsg(bin + " " + strings.Join(args, " "))
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pid := cmd.Process.Pid
+	if err := os.WriteFile(p.pidFile(), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return err
+	}
+	// the chain outlives the server or the cli that started it
+	if err := cmd.Process.Release(); err != nil {
+		return err
+	}
+	if err := waitReady(ctx, p.net); err != nil {
+		return fmt.Errorf("%w, see %s", err, logFile.Name())
+	}
+	if !fresh {
+		return nil
+	}
+	accounts := map[string]string{}
+	for address, amount := range p.cfg.Accounts {
+		accounts[address] = amount
+	}
+	if miner, err := minerAddress(p.cfg.MinerKey); err == nil {
+		accounts[miner.Hex()] = p.cfg.MinerPremintWei
+	}
+	for address, amount := range accounts {
+		wei, ok := new(big.Int).SetString(amount, 10)
+		if !ok || wei.Sign() == 0 {
+			continue
+		}
+		if err := p.Fund(ctx, common.HexToAddress(address), wei); err != nil {
+			return err
+		}
+		log15.Info("funded l1 account", "l1", p.cfg.Name, "account", address, "amount", wei)
+	}
+	return nil
+}
+
+func (p *localProvider) Stop() error {
+	content, err := os.ReadFile(p.pidFile())
+	if os.IsNotExist(err) {
+		return fmt.Errorf("l1 %s is not running", p.cfg.Name)
+	}
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return fmt.Errorf("invalid pid file %s, %w", p.pidFile(), err)
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	// an interrupt lets anvil dump its state and geth close its db
+	if err := process.Signal(os.Interrupt); err != nil {
+		log15.Warn("l1 process is gone", "l1", p.cfg.Name, "pid", pid, "err", err)
+	}
+	for i := 0; i < 30 && process.Signal(syscall.Signal(0)) == nil; i++ {
+		time.Sleep(time.Second)
+	}
+	return os.Remove(p.pidFile())
+}
+
+func (p *localProvider) reset() error {
+	if _, err := os.Stat(p.pidFile()); err == nil {
+		if err := p.Stop(); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(p.dir())
+}
+
+func (p *localProvider) ChainId(ctx context.Context) (*big.Int, error) {
+	return chainId(ctx, p.net)
+}
+
+// Fund sets the balance on anvil and sends from the unlocked dev account of
+// geth.
+func (p *localProvider) Fund(ctx context.Context, address common.Address, amount *big.Int) error {
+	ctx, cancel := context.WithTimeout(ctx, fundTimeout)
+	defer cancel()
+	if p.cfg.Provider == ProviderAnvil {
+		var balance hexutil.Big
+		if err := call(ctx, p.net, &balance, "eth_getBalance", address, "latest"); err != nil {
+			return err
+		}
+		total := new(big.Int).Add(balance.ToInt(), amount)
+		return call(ctx, p.net, nil, "anvil_setBalance", address, hexutil.EncodeBig(total))
+	}
+	return p.sendFromDev(ctx, &address, amount)
+}
+
+// Mine seals the blocks now, anvil on request and geth --dev with a
+// transaction of its dev account for each.
+func (p *localProvider) Mine(ctx context.Context, blocks int) error {
+	if p.cfg.Provider == ProviderAnvil {
+		return call(ctx, p.net, nil, "anvil_mine", hexutil.EncodeUint64(uint64(blocks)))
+	}
+	for i := 0; i < blocks; i++ {
+		if err := p.sendFromDev(ctx, nil, new(big.Int)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendFromDev sends amount from the dev account of geth to to, or to itself
+// when to is nil, and waits until it is included.
+func (p *localProvider) sendFromDev(ctx context.Context, to *common.Address, amount *big.Int) error {
+	var dev common.Address
+	if err := call(ctx, p.net, &dev, "eth_coinbase"); err != nil {
+		return err
+	}
+	if to == nil {
+		to = &dev
+	}
+	var hash common.Hash
+	err := call(ctx, p.net, &hash, "eth_sendTransaction", map[string]interface{}{
+		"from":  dev,
+		"to":    to,
+		"value": (*hexutil.Big)(amount),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send from the dev account %s, %w", dev, err)
+	}
+	client, err := ethclient.DialContext(ctx, p.net.PublicRpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	_, err = util.WaitReceipt(ctx, client, hash)
+	return err
+}
@@ -0,0 +1,194 @@
+package l1
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+)
+
+// Providers of an l1.
+const (
+	ProviderDocker   = "docker"
+	ProviderAnvil    = "anvil"
+	ProviderGethDev  = "geth-dev"
+	ProviderExternal = "external"
+
+	gethDevChainId = 1337
+	fundTimeout    = time.Minute * 2
+)
+
+// Provider runs or reaches an l1 the rollups settle on.
+type Provider interface {
+	Network() types.L1Net
+	// Start brings the l1 up unless it serves blocks already, an external l1
+	// is only checked.
+	Start(ctx context.Context) error
+	// Stop stops the l1 and keeps its data.
+	Stop() error
+	ChainId(ctx context.Context) (*big.Int, error)
+	// Fund adds amount to the balance of address.
+	Fund(ctx context.Context, address common.Address, amount *big.Int) error
+	// Mine returns once blocks more blocks are sealed.
+	Mine(ctx context.Context, blocks int) error
+}
+
+// devChain is a provider g1g2 l1 renders and resets.
+type devChain interface {
+	Provider
+	up(ctx context.Context) error
+	reset() error
+}
+
+// ProviderKind returns the provider of net, an l1 without one is external
+// unless it is the dev l1 of the config.
+func ProviderKind(net types.L1Net, dev *DevConfig) string {
+	if net.Provider != "" {
+		return net.Provider
+	}
+	if dev != nil && net.Name == dev.Name {
+		return dev.Provider
+	}
+	return ProviderExternal
+}
+
+// NewProvider returns the provider of net. dev configures the chains g1g2
+// runs itself, funderKey funds accounts on an external l1.
+func NewProvider(net types.L1Net, dev *DevConfig, overrideDir string, funderKey string) (Provider, error) {
+	switch kind := ProviderKind(net, dev); kind {
+	case ProviderExternal:
+		return &externalProvider{net: net, funderKey: funderKey}, nil
+	case ProviderDocker:
+		if dev == nil || net.Name != dev.Name {
+			return nil, fmt.Errorf("l1 %s is not the docker dev l1 of the config", net.Name)
+		}
+		return &dockerProvider{cfg: dev, overrideDir: overrideDir}, nil
+	case ProviderAnvil, ProviderGethDev:
+		cfg, err := localConfig(net, dev, kind)
+		if err != nil {
+			return nil, err
+		}
+		return &localProvider{cfg: cfg, net: net}, nil
+	default:
+		return nil, fmt.Errorf("l1 %s has an unknown provider %q", net.Name, kind)
+	}
+}
+
+// newDevChain returns the provider of the dev l1 of cfg.
+func newDevChain(cfg *DevConfig, overrideDir string) devChain {
+	if cfg.Provider == ProviderDocker {
+		return &dockerProvider{cfg: cfg, overrideDir: overrideDir}
+	}
+	return &localProvider{cfg: cfg, net: cfg.Network()}
+}
+
+// localConfig is the dev config running net as a local process, the dev l1
+// itself or an l1 of a spec on a port of its own.
+func localConfig(net types.L1Net, dev *DevConfig, kind string) (*DevConfig, error) {
+	cfg := DefaultDevConfig
+	if dev != nil {
+		cfg = *dev
+	}
+	if dev != nil && net.Name == dev.Name {
+		return &cfg, nil
+	}
+	u, err := url.Parse(net.PublicRpcUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rpc of l1 %s, %w", net.Name, err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return nil, fmt.Errorf("the rpc of l1 %s has no port, %s", net.Name, net.PublicRpcUrl)
+	}
+	cfg.Name = net.Name
+	cfg.Provider = kind
+	cfg.ChainId = net.ChainId
+	cfg.HttpPort = port
+	cfg.Accounts = nil
+	return &cfg, cfg.Validate()
+}
+
+func chainId(ctx context.Context, net types.L1Net) (*big.Int, error) {
+	client, err := ethclient.DialContext(ctx, net.PublicRpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.ChainID(ctx)
+}
+
+func call(ctx context.Context, net types.L1Net, result interface{}, method string, args ...interface{}) error {
+	client, err := rpc.DialContext(ctx, net.PublicRpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.CallContext(ctx, result, method, args...)
+}
+
+// waitBlocks returns once the head of net advanced by blocks, for the l1s
+// that seal on their own period.
+func waitBlocks(ctx context.Context, net types.L1Net, blocks int) error {
+	client, err := ethclient.DialContext(ctx, net.PublicRpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	start, err := client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		if head >= start+uint64(blocks) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("l1 %s sealed %d of %d blocks, %w", net.Name, head-start, blocks, ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// transfer sends amount from key to address and waits until it is included.
+func transfer(ctx context.Context, net types.L1Net, key string, to common.Address, amount *big.Int) error {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid funder key of l1 %s, %w", net.Name, err)
+	}
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	if from == to {
+		return fmt.Errorf("%s funds the accounts of l1 %s and cannot fund itself", from, net.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, fundTimeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, net.PublicRpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return err
+	}
+	tx, err := util.SendTransfer(ctx, client, privateKey, nonce, to, amount)
+	if err != nil {
+		return fmt.Errorf("failed to fund %s from %s, %w", to, from, err)
+	}
+	_, err = util.WaitReceipt(ctx, client, tx.Hash())
+	return err
+}
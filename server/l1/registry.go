@@ -0,0 +1,82 @@
+package l1
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+)
+
+// RegistryPath is the file of the l1 network registry. Rollup specs that
+// only name their l1 are resolved against it.
+func RegistryPath() string {
+	return path.Join(util.ToAbsolutePath(Dir), "networks.json")
+}
+
+// Networks returns the registered l1 networks.
+func Networks() ([]types.L1Net, error) {
+	networks := []types.L1Net{}
+	err := util.ReadJSONTo(&networks, RegistryPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return networks, nil
+}
+
+func LookupNetwork(name string) (*types.L1Net, error) {
+	networks, err := Networks()
+	if err != nil {
+		return nil, err
+	}
+	for _, net := range networks {
+		if net.Name == name {
+			return &net, nil
+		}
+	}
+	return nil, fmt.Errorf("l1 network %s is not registered", name)
+}
+
+// RegisterNetwork adds net to the registry or replaces the network with its
+// name.
+func RegisterNetwork(net types.L1Net) error {
+	networks, err := Networks()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range networks {
+		if networks[i].Name == net.Name {
+			networks[i] = net
+			replaced = true
+		}
+	}
+	if !replaced {
+		networks = append(networks, net)
+	}
+	if err := os.MkdirAll(path.Dir(RegistryPath()), 0755); err != nil {
+		return err
+	}
+	return util.WriteJSONTo(networks, RegistryPath())
+}
+
+// ResolveNetwork completes an l1 given by its name only from the registry.
+// An empty l1 is the dev l1, as registered by g1g2 l1 up or with the
+// settings of dev otherwise.
+func ResolveNetwork(net types.L1Net, dev *DevConfig) (types.L1Net, error) {
+	if net.Name == "" {
+		net.Name = dev.Name
+	}
+	if net.PublicRpcUrl != "" {
+		return net, nil
+	}
+	registered, err := LookupNetwork(net.Name)
+	if err == nil {
+		return *registered, nil
+	}
+	if net.Name == dev.Name {
+		return dev.Network(), nil
+	}
+	return net, fmt.Errorf("%w, run g1g2 l1 up or give the rpc urls of the l1", err)
+}
@@ -0,0 +1,308 @@
+package l2
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+)
+
+type ApplyResult struct {
+	Rollup   *types.Rollup
+	Created  bool
+	Changed  []string
+	Services []string
+}
+
+func (r *ApplyResult) UpToDate() bool {
+	return !r.Created && len(r.Changed) == 0
+}
+
+// ApplyRollup reconciles the rollup with the spec. A missing rollup goes
+// through the full create pipeline. An existing one gets its artifacts
+// re-rendered and only the services whose artifacts changed are rebuilt and
+// restarted.
+func ApplyRollup(
+	ctx context.Context,
+	config *L2Config,
+	spec *types.RollupSpec,
+	db *db.LocalFileDatabase,
+) (*ApplyResult, error) {
+	current, err := db.GetRollupByName(spec.Name)
+	if err != nil {
+		rollup, err := CreateRollupFromSpec(ctx, config, spec, db)
+		if err != nil {
+			return nil, err
+		}
+		err = RunRollup(config, rollup, db)
+		return &ApplyResult{Rollup: rollup, Created: true}, err
+	}
+	if current.L1Rollup == "" {
+		return nil, fmt.Errorf("rollup %s has no l1 contracts, delete it and apply again", spec.Name)
+	}
+
+	// the rollup keeps its ports unless the spec moves them
+	if spec.Ports.Base == 0 {
+		pinned := *spec
+		pinned.Ports.Base = current.BasePort
+		spec = &pinned
+	}
+	rollups, err := db.GetRollups()
+	if err != nil {
+		return nil, err
+	}
+	desired, err := rollupFromSpec(spec, config, rollups)
+	if err != nil {
+		return nil, err
+	}
+	// the default does not turn on a faucet the rollup was created without
+	if current.FaucetAddress == "" && spec.Faucet.Enabled == nil {
+		disabled := false
+		desired.Faucet.Enabled = &disabled
+		desired.FaucetAddress = ""
+	}
+	if err := checkImmutable(current, desired); err != nil {
+		return nil, err
+	}
+	updated := *current
+	updated.BeneficiaryAddress = desired.BeneficiaryAddress
+	// images stay pinned to what the rollup runs unless the spec asks for a
+	// version, so changing the defaults of the config does not upgrade it
+	if spec.Images.Execution != "" {
+		setImage(&updated.ExecutionImage, &updated.ExecutionDigest, desired.ExecutionImage)
+	}
+	if spec.Images.Consensus != "" {
+		setImage(&updated.ConsensusImage, &updated.ConsensusDigest, desired.ConsensusImage)
+	}
+	if spec.Images.Prover != "" || current.ProverImage == "" || desired.ProverImage == "" {
+		setImage(&updated.ProverImage, &updated.ProverDigest, desired.ProverImage)
+	}
+	updated.BasePort = desired.BasePort
+	updated.Consensus = desired.Consensus
+	updated.Node = desired.Node
+	updated.Replicas = desired.Replicas
+	updated.Faucet = desired.Faucet
+	updated.Explorer = desired.Explorer
+	updated.Icon = desired.Icon
+	updated.NativeCurrency = desired.NativeCurrency
+	return reconcileRollup(ctx, config, current, &updated, db)
+}
+
+// setImage switches to image and drops the digest pinned for the old one.
+func setImage(image, digest *string, to string) {
+	if *image != to {
+		*image = to
+		*digest = ""
+	}
+}
+
+// ScaleReplicas changes the number of replica nodes of a running rollup.
+func ScaleReplicas(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	replicas int,
+	db *db.LocalFileDatabase,
+) (*ApplyResult, error) {
+	current, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if current.L1Rollup == "" {
+		return nil, fmt.Errorf("rollup %s is not provisioned yet", name)
+	}
+	if err := validateReplicas(replicas); err != nil {
+		return nil, err
+	}
+	updated := *current
+	updated.Replicas = replicas
+	return reconcileRollup(ctx, config, current, &updated, db)
+}
+
+// RotateJwtSecret replaces the engine api secret of a rollup and restarts
+// the execution nodes and the consensus client to pick it up.
+func RotateJwtSecret(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	current, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if current.L1Rollup == "" {
+		return nil, fmt.Errorf("rollup %s is not provisioned yet", name)
+	}
+	// a refused rotation must leave the secret on disk alone
+	if err := checkReconcilable(current); err != nil {
+		return nil, err
+	}
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), name)
+	err = builder.WriteJwtSecret(rollupDir, true)
+	if err != nil {
+		return nil, err
+	}
+	updated := *current
+	updated.JwtRotatedAt = time.Now()
+	// rollups rendered before the secret was mounted get their artifacts
+	// updated first
+	result, err := reconcileRollup(ctx, config, current, &updated, db)
+	if err != nil {
+		return nil, err
+	}
+
+	services := []string{SequencerService, "consensus"}
+	for _, index := range replicaIndexes(result.Rollup) {
+		services = append(services, replicaService(index))
+	}
+	err = builder.RestartServices(rollupDir, services)
+	if err != nil {
+		return nil, err
+	}
+	err = waitRollupRunning(ctx, config, result.Rollup)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rollup, nil
+}
+
+// checkReconcilable refuses to change the artifacts of stopped and archived
+// rollups.
+func checkReconcilable(current *types.Rollup) error {
+	if current.Step == types.Stopped || current.Step == types.Archived {
+		return fmt.Errorf("rollup %s is %s, start it first", current.Name, types.StepName(current.Step))
+	}
+	return nil
+}
+
+// reconcileRollup re-renders the artifacts of updated and rebuilds and
+// restarts only the services built from the artifacts that changed.
+func reconcileRollup(
+	ctx context.Context,
+	config *L2Config,
+	current, updated *types.Rollup,
+	db *db.LocalFileDatabase,
+) (*ApplyResult, error) {
+	if err := checkReconcilable(current); err != nil {
+		return nil, err
+	}
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), updated.Name)
+	before, err := util.HashDir(rollupDir)
+	if err != nil {
+		return nil, err
+	}
+	err = RenderRollup(updated, builder, db)
+	if err != nil {
+		return nil, err
+	}
+	after, err := util.HashDir(rollupDir)
+	if err != nil {
+		return nil, err
+	}
+	contextServices, err := composeBuildServices(rollupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{Rollup: updated}
+	services := map[string]bool{}
+	changed := map[string]bool{}
+	for file, hash := range after {
+		if before[file] != hash {
+			changed[file] = true
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed[file] = true
+		}
+	}
+	for file := range changed {
+		result.Changed = append(result.Changed, file)
+		dir := strings.Split(filepath.ToSlash(file), "/")[0]
+		for _, service := range contextServices[dir] {
+			services[service] = true
+		}
+	}
+	sort.Strings(result.Changed)
+	for service := range services {
+		result.Services = append(result.Services, service)
+	}
+	sort.Strings(result.Services)
+	setRpcUrls(updated)
+	if len(result.Changed) == 0 && reflect.DeepEqual(current, updated) {
+		return result, nil
+	}
+
+	if len(result.Changed) > 0 {
+		err = builder.UpdateRollup(rollupDir, result.Services)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the record follows the rendered artifacts even if the nodes do not
+	// come up
+	waitErr := waitRollupRunning(ctx, config, updated)
+	if err := db.UpdateRollup(updated); err != nil {
+		return nil, err
+	}
+	if waitErr != nil {
+		return nil, waitErr
+	}
+	return result, nil
+}
+
+// composeBuildServices maps each build context of the compose file of dir to
+// the services built from it.
+func composeBuildServices(dir string) (map[string][]string, error) {
+	compose, err := readComposeFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	services := map[string][]string{}
+	for name, service := range compose.Services {
+		if service.Build != nil {
+			services[service.Build.Context] = append(services[service.Build.Context], name)
+		}
+	}
+	return services, nil
+}
+
+func checkImmutable(current, desired *types.Rollup) error {
+	if current.ChainId != desired.ChainId {
+		return fmt.Errorf("chain_id of rollup %s can not change from %d to %d", current.Name, current.ChainId, desired.ChainId)
+	}
+	if current.L1.Name != desired.L1.Name || current.L1.ChainId != desired.L1.ChainId {
+		return fmt.Errorf("l1 of rollup %s can not change from %s to %s", current.Name, current.L1.Name, desired.L1.Name)
+	}
+	// wallets funded on the running rollup are not part of the spec
+	if !reflect.DeepEqual(types.GenesisWallets(current.L2FundWallets), types.GenesisWallets(desired.L2FundWallets)) {
+		return fmt.Errorf("l2_wallets of rollup %s are part of the genesis and can not change", current.Name)
+	}
+	// a faucet can be turned off and on again, but only one premined on
+	// create can be turned on
+	if desired.Faucet.IsEnabled() && current.FaucetAddress == "" {
+		return fmt.Errorf("the faucet of rollup %s is part of the genesis and can only be enabled on create", current.Name)
+	}
+	if current.FaucetAddress != "" && current.Faucet.PremintWei != desired.Faucet.PremintWei {
+		return fmt.Errorf("faucet premint_wei of rollup %s is part of the genesis and can not change", current.Name)
+	}
+	return nil
+}
@@ -0,0 +1,600 @@
+package l2
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+const (
+	backupManifest  = "manifest.json"
+	backupRollup    = "rollup.json"
+	backupContracts = "contracts.json"
+	backupBuildDir  = "build/"
+	backupVolumeDir = "volumes/"
+
+	// RelayVolume is the volume of the relay db the rollups of a host share.
+	RelayVolume = "relay-db-data"
+)
+
+// backupsDir holds the backup dirs of every rollup.
+func backupsDir() string {
+	return path.Join(util.ToAbsolutePath(BuildDir), "backups")
+}
+
+// BackupDir is where the archives of a rollup are written.
+func BackupDir(name string) string {
+	return path.Join(backupsDir(), name)
+}
+
+// BackupFile resolves file, a path as listed by ListBackups or relative to the
+// backups dir, and refuses anything but an archive inside the backups dir.
+func BackupFile(file string) (string, error) {
+	root, err := filepath.EvalSymlinks(backupsDir())
+	if err != nil {
+		return "", fmt.Errorf("no backups on this host, %w", err)
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(backupsDir(), file)
+	}
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(resolved, root+string(filepath.Separator)) || !strings.HasSuffix(resolved, ".tar.gz") {
+		return "", fmt.Errorf("%s is not a backup archive in %s", file, backupsDir())
+	}
+	return resolved, nil
+}
+
+// nodeVolume is the volume of the nodes of rollup name, they keep their data
+// under /data/<name> in it.
+func nodeVolume(name string) string {
+	return name + "_node_data"
+}
+
+// backupVolumes are the volumes holding the state of the rollup. The relay db
+// volume holds the rows of every rollup of the host, so it is left out.
+func backupVolumes(rollup *types.Rollup) []string {
+	return []string{nodeVolume(rollup.Name)}
+}
+
+// BackupRollup writes an archive of the rollup record, its contract record,
+// the rendered build dir and its volumes. An online rollup is stopped while
+// the volumes are copied so they are consistent, and started again after. A
+// rollup that does not start again is recorded as stopped and the failure is
+// returned.
+func BackupRollup(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+	volumes VolumeRuntime,
+) (backup *types.RollupBackup, err error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.Step < types.Online {
+		return nil, fmt.Errorf("rollup %s is not provisioned yet", name)
+	}
+	contracts, err := db.GetRollupContracts(rollup.L1.ChainId, rollup.ChainId)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), name)
+
+	if rollup.Step == types.Online {
+		err = builder.ComposeRollup(rollupDir, "stop")
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			startErr := builder.ComposeRollup(rollupDir, "start")
+			if startErr != nil {
+				rollup.Step = types.Stopped
+				rollup.Status = types.StepName(types.Stopped)
+				if err := db.UpdateRollup(rollup); err != nil {
+					log15.Error("failed to record rollup stopped", "name", name, "err", err)
+				}
+				startErr = fmt.Errorf("rollup %s is stopped, it failed to start after the backup, %w", name, startErr)
+			} else if startErr = waitRollupRunning(ctx, config, rollup); startErr != nil {
+				startErr = fmt.Errorf("rollup %s does not serve rpc after the backup, %w", name, startErr)
+			}
+			if startErr == nil {
+				return
+			}
+			if err != nil {
+				log15.Error("failed to start rollup after backup", "name", name, "err", startErr)
+				return
+			}
+			backup, err = nil, fmt.Errorf("backup %s is written but %w", backup.File, startErr)
+		}()
+	}
+
+	backup = &types.RollupBackup{
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		Volumes:   backupVolumes(rollup),
+	}
+	err = os.MkdirAll(BackupDir(name), 0755)
+	if err != nil {
+		return nil, err
+	}
+	backup.File = path.Join(BackupDir(name), fmt.Sprintf("%s-%s.tar.gz", name, backup.CreatedAt.Format("20060102T150405Z")))
+	err = writeBackup(backup, rollup, contracts, rollupDir, volumes)
+	if err != nil {
+		os.Remove(backup.File)
+		return nil, err
+	}
+	info, err := os.Stat(backup.File)
+	if err != nil {
+		return nil, err
+	}
+	backup.Size = info.Size()
+	return backup, nil
+}
+
+func writeBackup(
+	backup *types.RollupBackup,
+	rollup *types.Rollup,
+	contracts *types.RollupContracts,
+	rollupDir string,
+	volumes VolumeRuntime,
+) error {
+	f, err := os.Create(backup.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	// the records go first so a restore knows the rollup before its data
+	records := []struct {
+		name string
+		v    interface{}
+	}{
+		{backupManifest, backup},
+		{backupRollup, rollup},
+		{backupContracts, contracts},
+	}
+	for _, record := range records {
+		content, err := json.MarshalIndent(record.v, "", " ")
+		if err != nil {
+			return err
+		}
+		if err := writeTarFile(tw, record.name, 0644, int64(len(content)), bytes.NewReader(content)); err != nil {
+			return err
+		}
+	}
+
+	err = filepath.Walk(rollupDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(rollupDir, p)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writeTarFile(tw, backupBuildDir+filepath.ToSlash(rel), int64(info.Mode().Perm()), info.Size(), file)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range backup.Volumes {
+		if err := writeBackupVolume(tw, volume, volumes); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeBackupVolume spools the volume to a temp file first, as the tar
+// header needs its size.
+func writeBackupVolume(tw *tar.Writer, volume string, volumes VolumeRuntime) error {
+	tmp, err := os.CreateTemp("", volume+"-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := volumes.ExportVolume(volume, tmp); err != nil {
+		return fmt.Errorf("failed to export volume %s, %w", volume, err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return writeTarFile(tw, backupVolumeDir+volume+".tar", 0644, size, tmp)
+}
+
+func writeTarFile(tw *tar.Writer, name string, mode, size int64, r io.Reader) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, r)
+	return err
+}
+
+// ListBackups returns the archives of a rollup, newest first.
+func ListBackups(name string) ([]*types.RollupBackup, error) {
+	files, err := filepath.Glob(path.Join(BackupDir(name), "*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+	backups := []*types.RollupBackup{}
+	for _, file := range files {
+		backup, err := ReadBackup(file)
+		if err != nil {
+			log15.Warn("skip unreadable backup", "file", file, "err", err)
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// ReadBackup reads the manifest of the archive at file.
+func ReadBackup(file string) (*types.RollupBackup, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s has no %s", file, backupManifest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == backupManifest {
+			backup := &types.RollupBackup{}
+			if err := json.NewDecoder(tr).Decode(backup); err != nil {
+				return nil, err
+			}
+			info, err := f.Stat()
+			if err != nil {
+				return nil, err
+			}
+			backup.File = file
+			backup.Size = info.Size()
+			return backup, nil
+		}
+	}
+}
+
+// RestoreRollup recreates a rollup from an archive of BackupRollup, copied
+// into the backups dir of the host it was taken on or of another one, and
+// starts it. The rollup is restored
+// under name, or under the name it was backed up with when name is empty.
+// The balance of the faucet of a renamed rollup is moved to the faucet of
+// its new name.
+// Neither the rollup, its build dir nor its node volume may exist, and no
+// other rollup may run its chain.
+func RestoreRollup(
+	ctx context.Context,
+	config *L2Config,
+	file string,
+	name string,
+	db *db.LocalFileDatabase,
+	volumes VolumeRuntime,
+) (*types.Rollup, error) {
+	rollup, source, err := restoreBackup(ctx, config, file, name, db, volumes)
+	if err != nil {
+		return nil, err
+	}
+	rollup, err = StartRollup(ctx, config, rollup.Name, db)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.FaucetAddress != source.FaucetAddress {
+		if err := moveFaucet(ctx, config, source, rollup); err != nil {
+			return nil, fmt.Errorf("rollup %s is restored but its faucet is not funded, %w", rollup.Name, err)
+		}
+	}
+	return rollup, nil
+}
+
+// restoreBackup extracts the archive and records the rollup as archived, it
+// returns the record as it was backed up too. A rollup restored under another
+// name gets the faucet of that name. A failed restore removes the build dir
+// and the volumes it created.
+func restoreBackup(
+	ctx context.Context,
+	config *L2Config,
+	file string,
+	name string,
+	db *db.LocalFileDatabase,
+	volumes VolumeRuntime,
+) (rollup *types.Rollup, backedUp *types.Rollup, err error) {
+	file, err = BackupFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is not a rollup backup, %w", file, err)
+	}
+	tr := tar.NewReader(gz)
+
+	var contracts *types.RollupContracts
+	source := ""
+	rollupDir := ""
+	created := []string{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollupDir != "" {
+			if err := os.RemoveAll(rollupDir); err != nil {
+				log15.Error("failed to remove the build dir of a failed restore", "dir", rollupDir, "err", err)
+			}
+		}
+		for _, volume := range created {
+			if err := volumes.RemoveVolume(volume); err != nil {
+				log15.Error("failed to remove the volume of a failed restore", "volume", volume, "err", err)
+			}
+		}
+	}()
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case header.Name == backupManifest:
+		case header.Name == backupRollup:
+			rollup = &types.Rollup{}
+			if err := json.NewDecoder(tr).Decode(rollup); err != nil {
+				return nil, nil, err
+			}
+			source = rollup.Name
+			record := *rollup
+			backedUp = &record
+			if name != "" {
+				rollup.Name = name
+			}
+			// the name of an archive is trusted no more than the one asked for
+			if err := types.ValidateRollupName(rollup.Name); err != nil {
+				return nil, nil, err
+			}
+			if err := checkRestorable(rollup, db); err != nil {
+				return nil, nil, err
+			}
+			dir := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name)
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				return nil, nil, fmt.Errorf("build dir %s already exists, remove it to restore rollup %s", dir, rollup.Name)
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, nil, err
+			}
+			rollupDir = dir
+		case header.Name == backupContracts:
+			contracts = &types.RollupContracts{}
+			if err := json.NewDecoder(tr).Decode(contracts); err != nil {
+				return nil, nil, err
+			}
+		case rollup == nil:
+			return nil, nil, fmt.Errorf("%s has %s before %s", file, header.Name, backupRollup)
+		case strings.HasPrefix(header.Name, backupBuildDir):
+			err = extractBackupFile(rollupDir, strings.TrimPrefix(header.Name, backupBuildDir), os.FileMode(header.Mode), tr)
+			if err != nil {
+				return nil, nil, err
+			}
+		case header.Name == backupVolumeDir+RelayVolume+".tar":
+			// archives of older versions hold the relay db volume of the host
+			log15.Warn("skip the relay db volume of the backup", "rollup", rollup.Name)
+		case header.Name == backupVolumeDir+nodeVolume(source)+".tar":
+			volume := nodeVolume(rollup.Name)
+			exists, err := volumes.VolumeExists(volume)
+			if err != nil {
+				return nil, nil, err
+			}
+			if exists {
+				return nil, nil, fmt.Errorf("volume %s already exists, remove it to restore rollup %s", volume, rollup.Name)
+			}
+			created = append(created, volume)
+			data := renameDataDir(tr, source, rollup.Name)
+			err = volumes.ImportVolume(volume, data)
+			data.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to import volume %s, %w", volume, err)
+			}
+		default:
+			return nil, nil, fmt.Errorf("unexpected %s in %s", header.Name, file)
+		}
+	}
+	if rollup == nil || contracts == nil {
+		return nil, nil, fmt.Errorf("%s has no rollup or contract record", file)
+	}
+
+	if rollup.Name != source {
+		if rollup.FaucetAddress != "" {
+			rollup.FaucetAddress, err = faucetAddress(config, rollup.Name)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		// the artifacts name the containers, volumes and data dirs after the
+		// rollup
+		builder, err := NewBuilder(ctx, config)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := RenderRollup(rollup, builder, db); err != nil {
+			return nil, nil, err
+		}
+	}
+	err = db.AddRollupContracts(contracts)
+	if err != nil {
+		return nil, nil, err
+	}
+	rollup.Step = types.Archived
+	rollup.Status = types.StepName(types.Archived)
+	err = db.CreateRollup(rollup)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rollup, backedUp, nil
+}
+
+// checkRestorable refuses to restore a rollup over an existing one, next to
+// a rollup running its chain, they would share its l1 contracts, or on the
+// ports of another rollup.
+func checkRestorable(rollup *types.Rollup, db *db.LocalFileDatabase) error {
+	if _, err := db.GetRollupByName(rollup.Name); err == nil {
+		return fmt.Errorf("rollup %s already exists", rollup.Name)
+	}
+	rollups, err := db.GetRollups()
+	if err != nil {
+		return err
+	}
+	for _, r := range rollups {
+		if r.L1.ChainId == rollup.L1.ChainId && r.ChainId == rollup.ChainId {
+			return fmt.Errorf("rollup %s already runs chain %d", r.Name, r.ChainId)
+		}
+	}
+	return checkPorts(rollup, rollups)
+}
+
+func extractBackupFile(dir, name string, mode os.FileMode, r io.Reader) error {
+	dest := filepath.Join(dir, filepath.FromSlash(name))
+	if !strings.HasPrefix(dest, dir+string(filepath.Separator)) {
+		return fmt.Errorf("backup file %s escapes the build dir", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// renameDataDir moves the data dir of rollup from to the one of rollup to in
+// the node volume tar r. Closing the returned reader stops the rename.
+func renameDataDir(r io.Reader, from, to string) io.ReadCloser {
+	if from == to {
+		return io.NopCloser(r)
+	}
+	pr, pw := io.Pipe()
+	renamed := &renamedTar{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(renamed.done)
+		pw.CloseWithError(renameTarEntries(r, pw, from, to))
+	}()
+	return renamed
+}
+
+type renamedTar struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// Close waits for the rename to stop, so r can be read again.
+func (r *renamedTar) Close() error {
+	err := r.PipeReader.Close()
+	<-r.done
+	return err
+}
+
+func renameTarEntries(r io.Reader, w io.Writer, from, to string) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return err
+		}
+		header.Name = renameTarPath(header.Name, from, to)
+		if header.Typeflag == tar.TypeLink {
+			header.Linkname = renameTarPath(header.Linkname, from, to)
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// renameTarPath replaces the leading dir from of name with to.
+func renameTarPath(name, from, to string) string {
+	prefix := ""
+	if strings.HasPrefix(name, "./") {
+		prefix, name = "./", strings.TrimPrefix(name, "./")
+	}
+	if name == from || strings.HasPrefix(name, from+"/") {
+		name = to + strings.TrimPrefix(name, from)
+	}
+	return prefix + name
+}
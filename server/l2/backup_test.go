@@ -0,0 +1,356 @@
+package l2
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+)
+
+// memVolumes keeps volumes as tar archives in memory.
+type memVolumes struct {
+	volumes   map[string][]byte
+	failsWith error
+}
+
+func newMemVolumes() *memVolumes {
+	return &memVolumes{volumes: map[string][]byte{}}
+}
+
+func (m *memVolumes) VolumeExists(name string) (bool, error) {
+	_, ok := m.volumes[name]
+	return ok, nil
+}
+
+func (m *memVolumes) ExportVolume(name string, w io.Writer) error {
+	content, ok := m.volumes[name]
+	if !ok {
+		return errors.New("no such volume " + name)
+	}
+	_, err := w.Write(content)
+	return err
+}
+
+func (m *memVolumes) ImportVolume(name string, r io.Reader) error {
+	m.volumes[name] = nil
+	if m.failsWith != nil {
+		return m.failsWith
+	}
+	content, err := io.ReadAll(r)
+	m.volumes[name] = content
+	return err
+}
+
+func (m *memVolumes) RemoveVolume(name string) error {
+	delete(m.volumes, name)
+	return nil
+}
+
+func tarFiles(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"./", "./alpha/", "./alpha/l2_geth/"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		if err := writeTarFile(tw, name, 0644, int64(len(content)), strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func untarFiles(t *testing.T, content []byte) map[string]string {
+	files := map[string]string{}
+	tr := tar.NewReader(bytes.NewReader(content))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+}
+
+// backupConfig has the admin key the faucets of the tests derive from.
+var backupConfig = &L2Config{G1G2Admin: G1G2Admin{
+	L1AdminPK: "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80",
+}}
+
+// setupBackup runs the test in a work dir next to a contracts repo with a
+// stopped rollup alpha, rendered and with chain data in its node volume and a
+// faucet.
+func setupBackup(t *testing.T) (*db.LocalFileDatabase, *memVolumes, *types.Rollup) {
+	root := t.TempDir()
+	work := filepath.Join(root, "server")
+	if err := os.MkdirAll(filepath.Join(work, "build", "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	rollupDb := db.NewLocalDatabase(context.Background(), filepath.Join(work, "build", "db"))
+	faucet, err := faucetAddress(backupConfig, "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	rollup := &types.Rollup{
+		Name:            "alpha",
+		ChainId:         167001,
+		L1Rollup:        "0x0000000000000000000000000000000000000001",
+		ExecutionImage:  "g1g2/l2_geth:v1",
+		ExecutionDigest: "sha256:1111",
+		ConsensusImage:  "g1g2/consensus:v1",
+		ConsensusDigest: "sha256:2222",
+		CreatedAt:       time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		L1:              types.L1Net{Name: "l1_dev", ChainId: 1337},
+		Step:            types.Stopped,
+		Status:          types.StepName(types.Stopped),
+		BasePort:        10545,
+		Node:            types.DefaultNodeProfile,
+		Consensus:       types.ConsensusSpec{RelayDbUrl: DockerRelayDBUrl},
+		Faucet:          types.FaucetSpec{Enabled: &enabled, PremintWei: "1000"},
+		FaucetAddress:   faucet,
+	}
+	if err := rollupDb.CreateRollup(rollup); err != nil {
+		t.Fatal(err)
+	}
+	err = rollupDb.AddRollupContracts(&types.RollupContracts{L1ChainId: rollup.L1.ChainId, L2ChainId: rollup.ChainId, Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name)
+	if err := os.MkdirAll(path.Join(rollupDir, "l2_geth"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the genesis only exists in the build dir, as on a host the rollup was
+	// restored to
+	if err := os.WriteFile(path.Join(rollupDir, "l2_geth", L2GenesisFileName), []byte(`{"config":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	builder, err := NewBuilder(context.Background(), backupConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RenderRollup(rollup, builder, rollupDb); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes := newMemVolumes()
+	volumes.volumes[nodeVolume(rollup.Name)] = tarFiles(t, map[string]string{
+		"./alpha/l2_geth/chaindata": "blocks of alpha",
+	})
+	volumes.volumes[RelayVolume] = tarFiles(t, map[string]string{
+		"./relay": "rows of every rollup",
+	})
+	return rollupDb, volumes, rollup
+}
+
+func TestBackupRestoreUnderNewName(t *testing.T) {
+	rollupDb, volumes, rollup := setupBackup(t)
+	ctx := context.Background()
+
+	backup, err := BackupRollup(ctx, backupConfig, rollup.Name, rollupDb, volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(backup.Volumes, []string{"alpha_node_data"}) {
+		t.Fatalf("backed up volumes %v, want only the node volume", backup.Volumes)
+	}
+	// the rollup is lost, its backup is restored next to nothing else
+	if err := rollupDb.DeleteRollup(rollup.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, backedUp, err := restoreBackup(ctx, backupConfig, backup.File, "beta", rollupDb, volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backedUp.Name != "alpha" || backedUp.FaucetAddress != rollup.FaucetAddress {
+		t.Fatalf("backed up record is %s with faucet %s, want alpha with %s", backedUp.Name, backedUp.FaucetAddress, rollup.FaucetAddress)
+	}
+
+	// the faucet key derives from the name, the renamed rollup gets the
+	// faucet of beta so its drips are signed with the key of the record
+	want := *rollup
+	want.Name = "beta"
+	want.FaucetAddress, err = faucetAddress(backupConfig, "beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.FaucetAddress == rollup.FaucetAddress {
+		t.Fatal("the faucets of alpha and beta are the same")
+	}
+	want.Step = types.Archived
+	want.Status = types.StepName(types.Archived)
+	recorded, err := rollupDb.GetRollupByName("beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []*types.Rollup{restored, recorded} {
+		gotJson, _ := json.Marshal(got)
+		wantJson, _ := json.Marshal(&want)
+		if !bytes.Equal(gotJson, wantJson) {
+			t.Fatalf("restored rollup\n%s\nwant\n%s", gotJson, wantJson)
+		}
+	}
+
+	files := untarFiles(t, volumes.volumes["beta_node_data"])
+	if files["./beta/l2_geth/chaindata"] != "blocks of alpha" {
+		t.Fatalf("restored node volume has %v, want the chain data of alpha under beta", files)
+	}
+	for name := range files {
+		if strings.Contains(name, "alpha") {
+			t.Fatalf("restored node volume still has %s", name)
+		}
+	}
+	if got := untarFiles(t, volumes.volumes[RelayVolume])["./relay"]; got != "rows of every rollup" {
+		t.Fatalf("relay volume changed to %q", got)
+	}
+
+	for _, file := range []string{"l2_geth/nodekey", "jwt/jwtsecret", "l2_geth/" + L2GenesisFileName} {
+		source, err := os.ReadFile(path.Join(util.ToAbsolutePath(BuildDir), "alpha", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		target, err := os.ReadFile(path.Join(util.ToAbsolutePath(BuildDir), "beta", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(source, target) {
+			t.Fatalf("restored %s differs from the backup", file)
+		}
+	}
+	compose, err := os.ReadFile(path.Join(util.ToAbsolutePath(BuildDir), "beta", "docker-compose.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(compose), "beta_node_data") || strings.Contains(string(compose), "alpha") {
+		t.Fatalf("restored compose file is not rendered for beta:\n%s", compose)
+	}
+}
+
+func TestFailedRestoreRemovesWhatItCreated(t *testing.T) {
+	rollupDb, volumes, rollup := setupBackup(t)
+	ctx := context.Background()
+
+	backup, err := BackupRollup(ctx, backupConfig, rollup.Name, rollupDb, volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := restoreBackup(ctx, backupConfig, backup.File, "beta", rollupDb, volumes); err == nil {
+		t.Fatal("restored a rollup next to the rollup running its chain")
+	}
+	if err := rollupDb.DeleteRollup(rollup.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes.failsWith = errors.New("disk full")
+	if _, _, err := restoreBackup(ctx, backupConfig, backup.File, "beta", rollupDb, volumes); err == nil {
+		t.Fatal("restore succeeded although the volume import failed")
+	}
+	if _, ok := volumes.volumes["beta_node_data"]; ok {
+		t.Fatal("failed restore left its node volume")
+	}
+	if _, err := os.Stat(path.Join(util.ToAbsolutePath(BuildDir), "beta")); !os.IsNotExist(err) {
+		t.Fatalf("failed restore left its build dir, %v", err)
+	}
+	if _, err := rollupDb.GetRollupByName("beta"); err == nil {
+		t.Fatal("failed restore recorded the rollup")
+	}
+}
+
+func TestRestoreRejectsUnsafeNames(t *testing.T) {
+	rollupDb, volumes, rollup := setupBackup(t)
+	ctx := context.Background()
+
+	backup, err := BackupRollup(ctx, backupConfig, rollup.Name, rollupDb, volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rollupDb.DeleteRollup(rollup.Name); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".", "..", "../alpha", "Beta", "-beta"} {
+		if _, _, err := restoreBackup(ctx, backupConfig, backup.File, name, rollupDb, volumes); err == nil {
+			t.Fatalf("restored a rollup named %q", name)
+		}
+	}
+	// the build dir of alpha is left, restoring over it must not remove it
+	if _, _, err := restoreBackup(ctx, backupConfig, backup.File, "", rollupDb, volumes); err == nil {
+		t.Fatal("restored over an existing build dir")
+	}
+	for _, dir := range []string{BuildDir, path.Join(BuildDir, "db"), path.Join(BuildDir, "alpha", "l2_geth")} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Fatalf("restore removed %s, %v", dir, err)
+		}
+	}
+}
+
+func TestRestoreOnlyReadsBackups(t *testing.T) {
+	rollupDb, volumes, rollup := setupBackup(t)
+	ctx := context.Background()
+
+	backup, err := BackupRollup(ctx, backupConfig, rollup.Name, rollupDb, volumes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := path.Join(util.ToAbsolutePath(BuildDir), "alpha.tar.gz")
+	content, err := os.ReadFile(backup.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(outside, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, path.Join(BackupDir("alpha"), "link.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{outside, "../alpha.tar.gz", "alpha/link.tar.gz", "/etc/passwd"} {
+		if _, err := BackupFile(file); err == nil {
+			t.Fatalf("accepted %s as a backup", file)
+		}
+	}
+	relative, err := filepath.Rel(backupsDir(), backup.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{backup.File, relative} {
+		if _, err := BackupFile(file); err != nil {
+			t.Fatalf("refused backup %s, %v", file, err)
+		}
+	}
+}
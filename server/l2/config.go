@@ -2,8 +2,12 @@ package l2
 
 import (
 	"fmt"
+	"math/big"
 	"os"
+	"time"
 
+	"github.com/g1g2-lab/automation/l1"
+	"github.com/g1g2-lab/automation/types"
 	"gopkg.in/yaml.v2"
 )
 
@@ -15,16 +19,136 @@ type G1G2Admin struct {
 type DockerImageConfig struct {
 	ExecutionBase string `yaml:"execution_base"`
 	ConsensusBase string `yaml:"consensus_base"`
+	ProverBase    string `yaml:"prover_base"`
 }
 
 type NodeConfig struct {
 	BasePort int `yaml:"base_port"`
+	// Profile holds the defaults of the l2 execution node of every rollup.
+	Profile types.NodeProfile `yaml:"profile"`
+}
+
+// TemplateConfig points at a dir whose files replace the embedded templates
+// with the same relative path, e.g. consensus/Dockerfile_tmpl.
+type TemplateConfig struct {
+	OverrideDir string `yaml:"override_dir"`
+}
+
+// ProverPool is a prover-rpcd shared by rollups. Containers reach it at
+// InternalRpcUrl, the server at PublicRpcUrl for status.
+type ProverPool struct {
+	InternalRpcUrl string `yaml:"internal_rpc"`
+	PublicRpcUrl   string `yaml:"public_rpc"`
+}
+
+type ProverConfig struct {
+	Pools map[string]ProverPool `yaml:"pools"`
+}
+
+// VerifyConfig bounds the checks a rollup passes before it is online. Zero
+// values take the defaults of DefaultVerifyConfig.
+type VerifyConfig struct {
+	// Disabled marks rollups online without verifying them. They can still
+	// be verified on demand.
+	Disabled bool `yaml:"disabled"`
+	// StartTimeout bounds the wait for the nodes of a rollup to serve rpc
+	// once their containers are up.
+	StartTimeout       time.Duration `yaml:"start_timeout"`
+	BlocksTimeout      time.Duration `yaml:"blocks_timeout"`
+	TransactionTimeout time.Duration `yaml:"transaction_timeout"`
+	ProposalTimeout    time.Duration `yaml:"proposal_timeout"`
+	ProofTimeout       time.Duration `yaml:"proof_timeout"`
+	BridgeTimeout      time.Duration `yaml:"bridge_timeout"`
+	// DepositWei and RelayFeeWei are the deposit of the bridge check.
+	DepositWei  string `yaml:"deposit_wei"`
+	RelayFeeWei string `yaml:"relay_fee_wei"`
+	// FundWei is what the admin key sends the account of the checks when it
+	// holds less than a check needs, so the admin only sends now and then.
+	FundWei string `yaml:"fund_wei"`
+}
+
+var DefaultVerifyConfig = VerifyConfig{
+	StartTimeout:       time.Minute * 5,
+	BlocksTimeout:      time.Minute,
+	TransactionTimeout: time.Minute,
+	ProposalTimeout:    time.Minute * 3,
+	ProofTimeout:       time.Minute * 10,
+	BridgeTimeout:      time.Minute * 5,
+	DepositWei:         "1000000000000000",
+	RelayFeeWei:        "20000000000000000",
+	FundWei:            "1000000000000000000",
+}
+
+// WithDefaults fills the zero values of c from DefaultVerifyConfig.
+func (c VerifyConfig) WithDefaults() VerifyConfig {
+	defaults := DefaultVerifyConfig
+	for _, d := range []struct {
+		v   *time.Duration
+		def time.Duration
+	}{
+		{&c.StartTimeout, defaults.StartTimeout},
+		{&c.BlocksTimeout, defaults.BlocksTimeout},
+		{&c.TransactionTimeout, defaults.TransactionTimeout},
+		{&c.ProposalTimeout, defaults.ProposalTimeout},
+		{&c.ProofTimeout, defaults.ProofTimeout},
+		{&c.BridgeTimeout, defaults.BridgeTimeout},
+	} {
+		if *d.v <= 0 {
+			*d.v = d.def
+		}
+	}
+	if c.DepositWei == "" {
+		c.DepositWei = defaults.DepositWei
+	}
+	if c.RelayFeeWei == "" {
+		c.RelayFeeWei = defaults.RelayFeeWei
+	}
+	if c.FundWei == "" {
+		c.FundWei = defaults.FundWei
+	}
+	return c
+}
+
+func (c VerifyConfig) Validate() error {
+	deposit, ok := new(big.Int).SetString(c.DepositWei, 10)
+	if !ok || deposit.Sign() <= 0 {
+		return fmt.Errorf("verify deposit_wei must be a positive integer, got %q", c.DepositWei)
+	}
+	fee, ok := new(big.Int).SetString(c.RelayFeeWei, 10)
+	if !ok || fee.Sign() < 0 {
+		return fmt.Errorf("verify relay_fee_wei must be a non-negative integer, got %q", c.RelayFeeWei)
+	}
+	fund, ok := new(big.Int).SetString(c.FundWei, 10)
+	if !ok || fund.Cmp(new(big.Int).Add(deposit, fee)) <= 0 {
+		return fmt.Errorf("verify fund_wei must be more than deposit_wei and relay_fee_wei, got %q", c.FundWei)
+	}
+	return nil
+}
+
+// ServerConfig configures the http server of g1g2 rollup server.
+type ServerConfig struct {
+	// TrustedProxies are the cidrs of the proxies whose X-Forwarded-For
+	// names the client ip, the faucet limits requests per client ip. Without
+	// them the client ip is the peer address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type L2Config struct {
+	ServerConfig      ServerConfig      `yaml:"server"`
 	G1G2Admin         G1G2Admin         `yaml:"g1g2_admin"`
 	DockerImageConfig DockerImageConfig `yaml:"docker_image"`
 	NodeConfig        NodeConfig        `yaml:"node"`
+	TemplateConfig    TemplateConfig    `yaml:"templates"`
+	// ConsensusConfig holds the defaults of the consensus client of every rollup.
+	ConsensusConfig types.ConsensusSpec `yaml:"consensus"`
+	ProverConfig    ProverConfig        `yaml:"prover"`
+	VerifyConfig    VerifyConfig        `yaml:"verify"`
+	// FaucetConfig holds the defaults of the faucet of every rollup.
+	FaucetConfig types.FaucetSpec `yaml:"faucet"`
+	// ExplorerConfig holds the defaults of the explorer of every rollup.
+	ExplorerConfig types.ExplorerSpec `yaml:"explorer"`
+	// L1Dev is the l1 g1g2 runs itself, see g1g2 l1.
+	L1Dev l1.DevConfig `yaml:"l1_dev"`
 }
 
 func NewL2ConfigFromFile(path string) (*L2Config, error) {
@@ -38,5 +162,10 @@ func NewL2ConfigFromFile(path string) (*L2Config, error) {
 			return nil, fmt.Errorf("failed to parse config file, %w", err)
 		}
 	}
+	cfg.L1Dev = cfg.L1Dev.WithDefaults(cfg.G1G2Admin.L1AdminPK)
+	cfg.VerifyConfig = cfg.VerifyConfig.WithDefaults()
+	if err := cfg.VerifyConfig.Validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
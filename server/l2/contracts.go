@@ -0,0 +1,276 @@
+package l2
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/inconshreveable/log15"
+)
+
+const (
+	artifactsDir = "packages/protocol/artifacts/hardhat/contracts/"
+	// contractsTimeout bounds the calls of ContractRegistry to both chains.
+	contractsTimeout = time.Second * 30
+	l1ProxyAdmin     = "G1G2ProxyAdmin"
+)
+
+// eip1967ImplementationSlot is the storage slot transparent proxies keep
+// their implementation in.
+var eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// contractArtifacts are the hardhat artifacts of the rollup contracts,
+// relative to artifactsDir.
+var contractArtifacts = map[string]string{
+	"ReceiptLibrary":     "library/Receipt.sol/ReceiptLibrary.json",
+	"TransactionLibrary": "library/Transaction.sol/TransactionLibrary.json",
+	"LibPropose":         "rollup/libs/LibPropose.sol/LibPropose.json",
+	"LibProve":           "rollup/libs/LibProve.sol/LibProve.json",
+	"LibOnChain":         "rollup/libs/LibOnChain.sol/LibOnChain.json",
+	"AddressManager":     "library/AddressManager.sol/AddressManager.json",
+	"L1Rollup":           "rollup/L1Rollup.sol/L1Rollup.json",
+	"L2Rollup":           "rollup/L2Rollup.sol/L2Rollup.json",
+	"CrossChainChannel":  "bridge/CrossChainChannel.sol/CrossChainChannel.json",
+	"L1Escrow":           "bridge/L1Escrow.sol/L1Escrow.json",
+	"L2Escrow":           "bridge/L2Escrow.sol/L2Escrow.json",
+	"G1G2ProxyAdmin":     "upgrade/G1G2ProxyAdmin.sol/G1G2ProxyAdmin.json",
+}
+
+type linkReference struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+type contractArtifact struct {
+	Abi              json.RawMessage `json:"abi"`
+	DeployedBytecode string          `json:"deployedBytecode"`
+	// DeployedLinkReferences are the placeholders of the libraries by file
+	// and library name.
+	DeployedLinkReferences map[string]map[string][]linkReference `json:"deployedLinkReferences"`
+}
+
+func loadArtifact(builder *RollupBuilder, name string) (*contractArtifact, error) {
+	file, ok := contractArtifacts[name]
+	if !ok {
+		return nil, fmt.Errorf("no artifact known for contract %s", name)
+	}
+	content, err := os.ReadFile(builder.ToContractRepoPath(artifactsDir + file))
+	if err != nil {
+		return nil, err
+	}
+	artifact := &contractArtifact{}
+	if err := json.Unmarshal(content, artifact); err != nil {
+		return nil, fmt.Errorf("artifact of %s: %w", name, err)
+	}
+	return artifact, nil
+}
+
+// codeHash hashes code the way the contracts repo records it: the keccak256
+// of its hex string, with the addresses of the linked libraries put back to
+// the placeholders of the artifact.
+func codeHash(code []byte, artifact *contractArtifact) string {
+	text := []byte("0x" + hex.EncodeToString(code))
+	if artifact != nil {
+		unlinked := strings.ToLower(artifact.DeployedBytecode)
+		for _, libraries := range artifact.DeployedLinkReferences {
+			for _, refs := range libraries {
+				for _, ref := range refs {
+					start, end := 2+ref.Start*2, 2+(ref.Start+ref.Length)*2
+					if end <= len(text) && end <= len(unlinked) {
+						copy(text[start:end], unlinked[start:end])
+					}
+				}
+			}
+		}
+	}
+	return crypto.Keccak256Hash(text).Hex()
+}
+
+// contractChecker reads the contracts of a rollup from one chain.
+type contractChecker struct {
+	ctx     context.Context
+	builder *RollupBuilder
+	client  *ethclient.Client
+	err     error
+}
+
+// check fills the abi of contract and compares the code at its
+// implementation, or at its address when it is not a proxy, with codeHash.
+func (c *contractChecker) check(contract *types.RollupContract) {
+	artifact, err := loadArtifact(c.builder, contract.Name)
+	if err != nil {
+		log15.Debug("contract artifact unavailable", "contract", contract.Name, "err", err)
+		artifact = nil
+	} else {
+		contract.Abi = artifact.Abi
+	}
+	contract.Code = types.CodeUnchecked
+	if c.err != nil {
+		contract.CodeDetail = c.err.Error()
+		return
+	}
+	codeAddress := contract.Address
+	if contract.Implementation != "" {
+		slot, err := c.client.StorageAt(c.ctx, common.HexToAddress(contract.Address), eip1967ImplementationSlot, nil)
+		if err != nil {
+			contract.CodeDetail = fmt.Sprintf("read implementation: %v", err)
+			return
+		}
+		onChain := common.BytesToAddress(slot)
+		contract.OnChainImplementation = onChain.Hex()
+		if onChain != common.HexToAddress(contract.Implementation) {
+			contract.Code = types.CodeMismatch
+			contract.CodeDetail = fmt.Sprintf("proxy points at %s instead of %s", onChain.Hex(), contract.Implementation)
+			return
+		}
+		codeAddress = contract.Implementation
+	}
+	if contract.CodeHash == "" {
+		contract.CodeDetail = "no code hash recorded"
+		return
+	}
+	code, err := c.client.CodeAt(c.ctx, common.HexToAddress(codeAddress), nil)
+	if err != nil {
+		contract.CodeDetail = fmt.Sprintf("read code: %v", err)
+		return
+	}
+	if len(code) == 0 {
+		contract.Code = types.CodeMismatch
+		contract.CodeDetail = fmt.Sprintf("no code at %s", codeAddress)
+		return
+	}
+	switch {
+	case strings.EqualFold(codeHash(code, artifact), contract.CodeHash):
+		contract.Code = types.CodeMatch
+	case artifact == nil:
+		// the placeholders of linked libraries are only known from the
+		// artifact
+		contract.CodeDetail = "code differs and the artifact to unlink its libraries is unavailable"
+	default:
+		contract.Code = types.CodeMismatch
+		contract.CodeDetail = fmt.Sprintf("code at %s does not hash to the recorded code hash", codeAddress)
+	}
+}
+
+// ContractRegistry lists the l1 and l2 contracts of a rollup at its deployed
+// version with their abi, and checks the code on both chains still matches
+// the code hashes the contracts repo recorded. A chain that cannot be reached
+// leaves its contracts unchecked.
+func ContractRegistry(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+) (*types.RollupContractRegistry, error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.L1Rollup == "" {
+		return nil, fmt.Errorf("rollup %s has no contracts deployed yet", name)
+	}
+	version := rollup.ContractsVersion
+	if version == 0 {
+		version = InitialContractsVersion
+	}
+	l1Logics, err := db.GetL1VersionedLogics(rollup.L1.ChainId, version)
+	if err != nil {
+		return nil, err
+	}
+	l2Logics, err := db.GetL2VersionedLogics(rollup.L1.ChainId, rollup.ChainId, version)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := func(layer, name, address string, logic types.ContractLogic) types.RollupContract {
+		return types.RollupContract{
+			Layer:          layer,
+			Name:           name,
+			Address:        address,
+			Implementation: logic.Address,
+			Version:        version,
+			CodeHash:       logic.CodeHash,
+		}
+	}
+	library := func(name string, logic types.ContractLogic) types.RollupContract {
+		return types.RollupContract{
+			Layer:    "l1",
+			Name:     name,
+			Address:  logic.Address,
+			Version:  version,
+			CodeHash: logic.CodeHash,
+		}
+	}
+	l1Contracts := []types.RollupContract{
+		proxy("l1", "AddressManager", rollup.L1AddressManager, l1Logics.AddressManager),
+		proxy("l1", "L1Rollup", rollup.L1Rollup, l1Logics.L1Rollup),
+		proxy("l1", "CrossChainChannel", rollup.L1Bridge, l1Logics.CrossChainChannel),
+		proxy("l1", "L1Escrow", rollup.L1Escrow, l1Logics.L1Escrow),
+		library("ReceiptLibrary", l1Logics.ReceiptLibrary),
+		library("TransactionLibrary", l1Logics.TransactionLibrary),
+		library("LibPropose", l1Logics.LibPropose),
+		library("LibProve", l1Logics.LibProve),
+		library("LibOnChain", l1Logics.LibOnChain),
+	}
+	// the proxy admin is shared by the rollups of an l1 and not versioned
+	if admin, err := db.GetL1Logic(rollup.L1.ChainId, l1ProxyAdmin); err != nil {
+		log15.Warn("l1 proxy admin not recorded", "l1ChainId", rollup.L1.ChainId, "err", err)
+	} else {
+		l1Contracts = append(l1Contracts, types.RollupContract{
+			Layer:    "l1",
+			Name:     l1ProxyAdmin,
+			Address:  admin.Address,
+			CodeHash: admin.CodeHash,
+		})
+	}
+	l2Contracts := []types.RollupContract{
+		proxy("l2", "AddressManager", rollup.L2AddressManager, l2Logics.AddressManager),
+		proxy("l2", "L2Rollup", rollup.L2Rollup, l2Logics.L2Rollup),
+		proxy("l2", "CrossChainChannel", rollup.L2Bridge, l2Logics.CrossChainChannel),
+		proxy("l2", "L2Escrow", rollup.L2Escrow, l2Logics.L2Escrow),
+		// predeployed by the genesis, no code hash is recorded for it
+		{Layer: "l2", Name: l1ProxyAdmin, Address: types.L2ProxyAdminAddr},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, contractsTimeout)
+	defer cancel()
+	for _, chain := range []struct {
+		url       string
+		contracts []types.RollupContract
+	}{
+		{rollup.L1.PublicRpcUrl, l1Contracts},
+		{rollup.RpcUrl, l2Contracts},
+	} {
+		checker := &contractChecker{ctx: ctx, builder: builder}
+		if chain.url == "" {
+			checker.err = fmt.Errorf("chain has no rpc url")
+		} else if checker.client, checker.err = ethclient.DialContext(ctx, chain.url); checker.err == nil {
+			defer checker.client.Close()
+		}
+		for i := range chain.contracts {
+			checker.check(&chain.contracts[i])
+		}
+	}
+
+	return &types.RollupContractRegistry{
+		Name:      rollup.Name,
+		L1ChainId: rollup.L1.ChainId,
+		L2ChainId: rollup.ChainId,
+		Version:   version,
+		CheckedAt: time.Now().UTC(),
+		Contracts: append(l1Contracts, l2Contracts...),
+	}, nil
+}
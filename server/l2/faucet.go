@@ -0,0 +1,221 @@
+package l2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+// ErrFaucetLimited is returned when a rate limit or the daily cap of a
+// faucet refuses a request.
+var ErrFaucetLimited = errors.New("faucet limit reached")
+
+// derivedKey derives the account of rollup name for purpose from the admin
+// key, so its key is never stored or served.
+func derivedKey(config *L2Config, purpose, name string) (*ecdsa.PrivateKey, error) {
+	admin, err := crypto.HexToECDSA(strings.TrimPrefix(config.G1G2Admin.L1AdminPK, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin key, %w", err)
+	}
+	seed := crypto.Keccak256([]byte(purpose), crypto.FromECDSA(admin), []byte(name))
+	return crypto.ToECDSA(seed)
+}
+
+// faucetKey is the key of the faucet account of rollup name.
+func faucetKey(config *L2Config, name string) (*ecdsa.PrivateKey, error) {
+	return derivedKey(config, "g1g2 faucet", name)
+}
+
+// faucetAddress is the address of the faucet key of rollup name.
+func faucetAddress(config *L2Config, name string) (string, error) {
+	key, err := faucetKey(config, name)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
+}
+
+// moveFaucet sends the balance of the faucet the rollup had under name from
+// to its current faucet, as the faucet key changes with the name. Twice the
+// fee is left behind so a rising gas price does not fail the transfer.
+func moveFaucet(ctx context.Context, config *L2Config, from *types.Rollup, rollup *types.Rollup) error {
+	key, err := faucetKey(config, from.Name)
+	if err != nil {
+		return err
+	}
+	source := crypto.PubkeyToAddress(key.PublicKey)
+	if source.Hex() != from.FaucetAddress {
+		return fmt.Errorf("the faucet of rollup %s was created with another admin key, its funds stay at %s", from.Name, from.FaucetAddress)
+	}
+	client, err := ethclient.DialContext(ctx, rollup.RpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return err
+	}
+	balance, err := client.BalanceAt(ctx, source, nil)
+	if err != nil {
+		return err
+	}
+	amount := new(big.Int).Sub(balance, new(big.Int).Mul(gasPrice, big.NewInt(2*21000)))
+	if amount.Sign() <= 0 {
+		log15.Warn("the previous faucet is empty", "rollup", rollup.Name, "faucet", source, "balance", balance)
+		return nil
+	}
+	nonce, err := client.PendingNonceAt(ctx, source)
+	if err != nil {
+		return err
+	}
+	tx, err := util.SendTransfer(ctx, client, key, nonce, common.HexToAddress(rollup.FaucetAddress), amount)
+	if err != nil {
+		return err
+	}
+	if _, err := util.WaitReceipt(ctx, client, tx.Hash()); err != nil {
+		return err
+	}
+	log15.Info("faucet moved", "rollup", rollup.Name, "from", source, "to", rollup.FaucetAddress, "amount", amount)
+	return nil
+}
+
+// Faucets serves the faucets of the rollups of a server. The rate limits and
+// daily caps are kept in memory and start over when the server restarts.
+type Faucets struct {
+	config *L2Config
+
+	// mu also serializes the transfers so the nonces of a faucet stay in
+	// order.
+	mu        sync.Mutex
+	addresses map[string]time.Time
+	ips       map[string]time.Time
+	day       string
+	spent     map[string]*big.Int
+}
+
+func NewFaucets(config *L2Config) *Faucets {
+	return &Faucets{
+		config:    config,
+		addresses: map[string]time.Time{},
+		ips:       map[string]time.Time{},
+		spent:     map[string]*big.Int{},
+	}
+}
+
+// Drip sends the amount of the faucet of rollup to address, asked from ip.
+func (f *Faucets) Drip(
+	ctx context.Context,
+	rollup *types.Rollup,
+	address string,
+	ip string,
+) (*types.FaucetDrip, error) {
+	if rollup.FaucetAddress == "" || !rollup.Faucet.IsEnabled() {
+		return nil, fmt.Errorf("rollup %s has no faucet", rollup.Name)
+	}
+	if rollup.Step != types.Online {
+		return nil, fmt.Errorf("rollup %s is %s", rollup.Name, types.StepName(rollup.Step))
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+	to := common.HexToAddress(address)
+	spec := rollup.Faucet
+	// records of old or forked rollups were not necessarily validated
+	amount, ok := new(big.Int).SetString(spec.AmountWei, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("the faucet of rollup %s has an invalid amount_wei %q", rollup.Name, spec.AmountWei)
+	}
+	dailyCap, ok := new(big.Int).SetString(spec.DailyCapWei, 10)
+	if !ok || dailyCap.Sign() <= 0 {
+		return nil, fmt.Errorf("the faucet of rollup %s has an invalid daily_cap_wei %q", rollup.Name, spec.DailyCapWei)
+	}
+	addressCooldown, ipCooldown := spec.Cooldowns()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	addressKey := rollup.Name + "/" + to.Hex()
+	ipKey := rollup.Name + "/" + ip
+	if wait := addressCooldown - now.Sub(f.addresses[addressKey]); wait > 0 {
+		return nil, fmt.Errorf("%w, %s can ask again in %s", ErrFaucetLimited, to.Hex(), wait.Round(time.Second))
+	}
+	if wait := ipCooldown - now.Sub(f.ips[ipKey]); wait > 0 {
+		return nil, fmt.Errorf("%w, %s can ask again in %s", ErrFaucetLimited, ip, wait.Round(time.Second))
+	}
+	if day := now.UTC().Format("2006-01-02"); day != f.day {
+		f.day = day
+		f.spent = map[string]*big.Int{}
+	}
+	spent := f.spent[rollup.Name]
+	if spent == nil {
+		spent = new(big.Int)
+	}
+	if new(big.Int).Add(spent, amount).Cmp(dailyCap) > 0 {
+		return nil, fmt.Errorf("%w, the faucet of rollup %s sent its daily cap of %s wei", ErrFaucetLimited, rollup.Name, dailyCap)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	tx, err := f.send(ctx, rollup, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	f.addresses[addressKey] = now
+	f.ips[ipKey] = now
+	f.spent[rollup.Name] = spent.Add(spent, amount)
+	log15.Info("faucet sent", "rollup", rollup.Name, "to", to, "amount", amount, "tx", tx.Hash())
+	return &types.FaucetDrip{
+		Name:      rollup.Name,
+		Address:   to.Hex(),
+		AmountWei: amount.String(),
+		TxHash:    tx.Hash().Hex(),
+	}, nil
+}
+
+// send checks the balance of the faucet covers amount and gas and sends it.
+func (f *Faucets) send(ctx context.Context, rollup *types.Rollup, to common.Address, amount *big.Int) (*ethtypes.Transaction, error) {
+	key, err := faucetKey(f.config, rollup.Name)
+	if err != nil {
+		return nil, err
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	if from.Hex() != rollup.FaucetAddress {
+		return nil, fmt.Errorf("the faucet of rollup %s was created with another admin key", rollup.Name)
+	}
+	client, err := ethclient.DialContext(ctx, rollup.RpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := client.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return nil, err
+	}
+	cost := new(big.Int).Add(amount, new(big.Int).Mul(gasPrice, big.NewInt(21000)))
+	if balance.Cmp(cost) < 0 {
+		return nil, fmt.Errorf("the faucet of rollup %s is empty, its balance is %s wei", rollup.Name, balance)
+	}
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	return util.SendTransfer(ctx, client, key, nonce, to, amount)
+}
@@ -0,0 +1,291 @@
+package l2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+const (
+	forkDir       = "fork"
+	forkStateFile = "state.json"
+	// accountRangePage is the most accounts debug_accountRange returns.
+	accountRangePage = 256
+)
+
+// ForkSpec checks that source can be forked with req and returns the spec of
+// the fork with its provenance. The fork copies the settings of source.
+func ForkSpec(
+	ctx context.Context,
+	config *L2Config,
+	sourceName string,
+	req *types.ForkRollupRequest,
+	db *db.LocalFileDatabase,
+) (*types.RollupSpec, *types.RollupFork, error) {
+	source, err := db.GetRollupByName(sourceName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rollup %s not found", sourceName)
+	}
+	if source.Step != types.Online {
+		return nil, nil, fmt.Errorf("rollup %s is %s, only an online rollup can be forked", sourceName, types.StepName(source.Step))
+	}
+	// only archive nodes keep the preimages the state export needs
+	if source.Node.GcMode != types.GcModeArchive {
+		return nil, nil, fmt.Errorf("rollup %s runs gc_mode %s, forking needs an archive node", sourceName, source.Node.GcMode)
+	}
+	if _, err := db.GetRollupByName(req.Name); err == nil {
+		return nil, nil, fmt.Errorf("rollup %s already exists", req.Name)
+	}
+	rollups, err := db.GetRollups()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, r := range rollups {
+		if r.L1.ChainId == source.L1.ChainId && r.ChainId == req.ChainId {
+			return nil, nil, fmt.Errorf("chain id %d is used by rollup %s", req.ChainId, r.Name)
+		}
+	}
+
+	var head hexutil.Uint64
+	if err := callRpc(ctx, source.SequencerRpcUrl, &head, "eth_blockNumber"); err != nil {
+		return nil, nil, fmt.Errorf("failed to get the head of rollup %s, %w", sourceName, err)
+	}
+	block := uint64(head)
+	if req.Block != nil {
+		if *req.Block > block {
+			return nil, nil, fmt.Errorf("block %d is ahead of the head %d of rollup %s", *req.Block, block, sourceName)
+		}
+		block = *req.Block
+	}
+	header := &struct {
+		Hash      string `json:"hash"`
+		StateRoot string `json:"stateRoot"`
+	}{}
+	if err := callRpc(ctx, source.SequencerRpcUrl, header, "eth_getBlockByNumber", hexutil.EncodeUint64(block), false); err != nil {
+		return nil, nil, fmt.Errorf("failed to get block %d of rollup %s, %w", block, sourceName, err)
+	}
+
+	spec := &types.RollupSpec{
+		Name:               req.Name,
+		ChainId:            req.ChainId,
+		L1:                 source.L1,
+		BeneficiaryAddress: source.BeneficiaryAddress,
+		L2FundWallets:      types.GenesisWallets(source.L2FundWallets),
+		Images: types.ImageSpec{
+			Execution: imageVersion(source.ExecutionImage),
+			Consensus: imageVersion(source.ConsensusImage),
+			Prover:    imageVersion(source.ProverImage),
+		},
+		Ports:     types.PortSpec{Base: req.BasePort},
+		Consensus: source.Consensus,
+		Node:      source.Node,
+		Replicas:  source.Replicas,
+		Faucet:    source.Faucet,
+		// the fork gets its own network name, label and port
+		Explorer: types.ExplorerSpec{
+			Enabled: source.Explorer.Enabled,
+			Coin:    source.Explorer.Coin,
+			Logo:    source.Explorer.Logo,
+		},
+		Icon:           source.Icon,
+		NativeCurrency: source.NativeCurrency,
+	}
+	rollup, err := rollupFromSpec(spec, config, rollups)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the fork is created with the ports it was planned with
+	spec.Ports.Base = rollup.BasePort
+	fork := &types.RollupFork{
+		Source:        source.Name,
+		SourceChainId: source.ChainId,
+		Block:         block,
+		BlockHash:     header.Hash,
+		StateRoot:     header.StateRoot,
+	}
+	return spec, fork, nil
+}
+
+func imageVersion(image string) string {
+	if image == "" {
+		return ""
+	}
+	return image[strings.LastIndex(image, ":")+1:]
+}
+
+// ForkRollup exports the state of the source of fork, then deploys fresh l1
+// contracts for the fork and renders it with a genesis holding that state.
+// The fork starts from block 0, the block history of the source is not
+// carried over. exported is called once the state of the source is exported,
+// the source is not used after.
+func ForkRollup(
+	ctx context.Context,
+	config *L2Config,
+	spec *types.RollupSpec,
+	fork *types.RollupFork,
+	db *db.LocalFileDatabase,
+	exported func(),
+) (*types.Rollup, error) {
+	source, err := db.GetRollupByName(fork.Source)
+	if err != nil {
+		return nil, err
+	}
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := db.GetRollups()
+	if err != nil {
+		return nil, err
+	}
+	rollup, err := rollupFromSpec(spec, config, rollups)
+	if err != nil {
+		return nil, err
+	}
+	// run the exact images of the source
+	if rollup.ExecutionImage == source.ExecutionImage {
+		rollup.ExecutionDigest = source.ExecutionDigest
+	}
+	if rollup.ConsensusImage == source.ConsensusImage {
+		rollup.ConsensusDigest = source.ConsensusDigest
+	}
+	if rollup.ProverImage == source.ProverImage {
+		rollup.ProverDigest = source.ProverDigest
+	}
+	rollup.ForkedFrom = fork
+	rollup.Step = types.RollupInit
+	rollup.Status = "export state"
+	err = db.CreateRollup(rollup)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceDir := path.Join(util.ToAbsolutePath(BuildDir), source.Name)
+	stateFile := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name, forkDir, forkStateFile)
+	accounts, err := exportState(builder, source, sourceDir, fork.Block, stateFile)
+	if err != nil {
+		return rollup, err
+	}
+	exported()
+	fork.Accounts = accounts
+	fork.ForkedAt = time.Now().UTC()
+	log15.Info("exported rollup state", "source", source.Name, "block", fork.Block, "accounts", accounts)
+
+	rollup.Step = types.RollupDeployOnL1
+	rollup.Status = "deploy to l1"
+	err = db.UpdateRollup(rollup)
+	if err != nil {
+		return rollup, err
+	}
+	err = createRollupImpl(ctx, rollup, builder, db, config)
+	return rollup, err
+}
+
+// exportState writes the state of the sequencer of source at block to file
+// and returns the number of accounts.
+func exportState(
+	builder *RollupBuilder,
+	source *types.Rollup,
+	sourceDir string,
+	block uint64,
+	file string,
+) (int, error) {
+	dump := &state.Dump{Accounts: map[common.Address]state.DumpAccount{}}
+	var start []byte
+	for {
+		page, err := builder.AccountRange(sourceDir, source.Name, block, start, accountRangePage)
+		if err != nil {
+			return 0, fmt.Errorf("failed to export the state of rollup %s at block %d, %w", source.Name, block, err)
+		}
+		dump.Root = page.Root
+		for addr, account := range page.Accounts {
+			dump.Accounts[addr] = account
+		}
+		if len(page.Next) == 0 {
+			break
+		}
+		start = page.Next
+	}
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return 0, err
+	}
+	return len(dump.Accounts), util.WriteJSONTo(dump, file)
+}
+
+// mergeForkState adds the accounts of the state file to the genesis file.
+// Contracts of the genesis are kept, as they are bound to the l1 contracts
+// of the fork, every other account takes the state of the source.
+func mergeForkState(genesisFile, stateFile string) error {
+	dump := &state.Dump{}
+	if err := util.ReadJSONTo(dump, stateFile); err != nil {
+		return fmt.Errorf("failed to read the fork state, %w", err)
+	}
+	genesis := map[string]interface{}{}
+	if err := util.ReadJSONTo(&genesis, genesisFile); err != nil {
+		return err
+	}
+	alloc, _ := genesis["alloc"].(map[string]interface{})
+	if alloc == nil {
+		alloc = map[string]interface{}{}
+	}
+	keys := map[common.Address]string{}
+	for key := range alloc {
+		keys[common.HexToAddress(key)] = key
+	}
+
+	for addr, account := range dump.Accounts {
+		if key, ok := keys[addr]; ok {
+			if existing, _ := alloc[key].(map[string]interface{}); existing != nil {
+				if code, _ := existing["code"].(string); code != "" && code != "0x" {
+					continue
+				}
+			}
+			delete(alloc, key)
+		}
+		entry := map[string]interface{}{
+			"balance": account.Balance,
+			"nonce":   hexutil.EncodeUint64(account.Nonce),
+		}
+		if len(account.Code) > 0 {
+			entry["code"] = account.Code.String()
+		}
+		if len(account.Storage) > 0 {
+			storage := map[string]string{}
+			for slot, value := range account.Storage {
+				storage[slot.Hex()] = common.BytesToHash(common.FromHex(value)).Hex()
+			}
+			entry["storage"] = storage
+		}
+		alloc[addr.Hex()] = entry
+	}
+	genesis["alloc"] = alloc
+	return util.WriteJSONTo(genesis, genesisFile)
+}
+
+// parseConsoleJSON returns the first JSON object line of the output of a
+// geth console, which also prints the value of the expression and warnings.
+func parseConsoleJSON(out []byte, v interface{}) error {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, len(out)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "{") {
+			return json.Unmarshal([]byte(line), v)
+		}
+	}
+	return fmt.Errorf("no json in console output: %s", strings.TrimSpace(string(out)))
+}
@@ -0,0 +1,85 @@
+package l2
+
+import (
+	"context"
+	"path"
+
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+)
+
+// StopRollup stops the containers of a rollup, keeping containers and
+// volumes for StartRollup.
+func StopRollup(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	return transitionRollup(ctx, config, name, types.Stopped, db)
+}
+
+// StartRollup brings a stopped or archived rollup back online.
+func StartRollup(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	return transitionRollup(ctx, config, name, types.Online, db)
+}
+
+// ArchiveRollup removes the containers of a rollup, which frees its ports,
+// but keeps its data volumes, artifacts and contract record.
+func ArchiveRollup(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	return transitionRollup(ctx, config, name, types.Archived, db)
+}
+
+func transitionRollup(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	to int,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := types.CheckStepTransition(rollup.Step, to); err != nil {
+		return nil, err
+	}
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), name)
+	switch {
+	case to == types.Stopped:
+		err = builder.ComposeRollup(rollupDir, "stop")
+	case to == types.Archived:
+		err = builder.ComposeRollup(rollupDir, "down")
+	case rollup.Step == types.Stopped:
+		err = builder.ComposeRollup(rollupDir, "start")
+	default:
+		err = builder.ComposeRollup(rollupDir, "up -d")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if to == types.Online {
+		if err := waitRollupRunning(ctx, config, rollup); err != nil {
+			return nil, err
+		}
+	}
+	rollup.Step = to
+	rollup.Status = types.StepName(to)
+	err = db.UpdateRollup(rollup)
+	return rollup, err
+}
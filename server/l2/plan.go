@@ -0,0 +1,185 @@
+package l2
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"gopkg.in/yaml.v2"
+)
+
+type composeService struct {
+	Image string `yaml:"image"`
+	Build *struct {
+		Context    string `yaml:"context"`
+		Dockerfile string `yaml:"dockerfile"`
+	} `yaml:"build"`
+	Ports []string `yaml:"ports"`
+}
+
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+// PlanRollup renders every artifact of the spec into outDir and describes the
+// contracts, images and ports provisioning would touch. It neither deploys
+// contracts nor talks to docker. Contracts already recorded in the contracts
+// repo are used as is, the others get the zero address as placeholder.
+func PlanRollup(
+	ctx context.Context,
+	config *L2Config,
+	spec *types.RollupSpec,
+	db *db.LocalFileDatabase,
+	outDir string,
+) (*types.RollupPlan, error) {
+	builder, err := NewDryRunBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	outDir = util.ToAbsolutePath(outDir)
+	rollups, err := db.GetRollups()
+	if err != nil {
+		return nil, err
+	}
+	rollup, err := rollupFromSpec(spec, config, rollups)
+	if err != nil {
+		return nil, err
+	}
+	setRpcUrls(rollup)
+
+	deployed := true
+	contracts, err := db.GetRollupContracts(rollup.L1.ChainId, rollup.ChainId)
+	if err != nil {
+		deployed = false
+		placeholder := common.Address{}.Hex()
+		contracts = &types.RollupContracts{
+			L1ChainId: rollup.L1.ChainId,
+			L2ChainId: rollup.ChainId,
+			L1Proxies: types.L1Proxies{
+				AddressManager:    placeholder,
+				L1Rollup:          placeholder,
+				CrossChainChannel: placeholder,
+				L1Escrow:          placeholder,
+			},
+		}
+	}
+	setContractAddresses(rollup, &contracts.L1Proxies)
+
+	err = os.MkdirAll(outDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = RenderRollupTo(rollup, builder, db, outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &types.RollupPlan{
+		Rollup: rollup,
+		OutDir: outDir,
+		Contracts: []types.PlannedContract{
+			{Layer: "l1", Name: "AddressManager", Address: rollup.L1AddressManager, Deployed: deployed},
+			{Layer: "l1", Name: "L1Rollup", Address: rollup.L1Rollup, Deployed: deployed},
+			{Layer: "l1", Name: "CrossChainChannel", Address: rollup.L1Bridge, Deployed: deployed},
+			{Layer: "l1", Name: "L1Escrow", Address: rollup.L1Escrow, Deployed: deployed},
+			{Layer: "l2", Name: "AddressManager", Address: rollup.L2AddressManager, Deployed: deployed},
+			{Layer: "l2", Name: "L2Rollup", Address: rollup.L2Rollup, Deployed: deployed},
+			{Layer: "l2", Name: "CrossChainChannel", Address: rollup.L2Bridge, Deployed: deployed},
+			{Layer: "l2", Name: "L2Escrow", Address: rollup.L2Escrow, Deployed: deployed},
+		},
+	}
+	hashes, err := util.HashDir(outDir)
+	if err != nil {
+		return nil, err
+	}
+	for file := range hashes {
+		plan.Artifacts = append(plan.Artifacts, file)
+	}
+	sort.Strings(plan.Artifacts)
+
+	err = planCompose(plan, outDir)
+	return plan, err
+}
+
+func planCompose(plan *types.RollupPlan, dir string) error {
+	compose, err := readComposeFile(dir)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for name := range compose.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := compose.Services[name]
+		image := types.PlannedImage{Service: name, Image: service.Image}
+		if service.Build != nil {
+			image.Build = true
+			dockerfile := service.Build.Dockerfile
+			if dockerfile == "" {
+				dockerfile = "Dockerfile"
+			}
+			base, err := dockerfileBase(path.Join(dir, service.Build.Context, dockerfile))
+			if err != nil {
+				return err
+			}
+			image.Image = base
+		}
+		plan.Images = append(plan.Images, image)
+
+		for _, port := range service.Ports {
+			host, container := "", port
+			if i := strings.LastIndex(port, ":"); i >= 0 {
+				host, container = port[:i], port[i+1:]
+			}
+			plan.Ports = append(plan.Ports, types.PlannedPort{
+				Service:       name,
+				HostPort:      host,
+				ContainerPort: container,
+			})
+		}
+	}
+	return nil
+}
+
+func readComposeFile(dir string) (*composeFile, error) {
+	content, err := os.ReadFile(path.Join(dir, "docker-compose.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	compose := &composeFile{}
+	if err := yaml.Unmarshal(content, compose); err != nil {
+		return nil, fmt.Errorf("failed to parse docker-compose.yaml, %w", err)
+	}
+	return compose, nil
+}
+
+// dockerfileBase returns the image of the first FROM instruction.
+func dockerfileBase(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "FROM") {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no FROM instruction", file)
+}
@@ -0,0 +1,102 @@
+package l2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/g1g2-lab/automation/types"
+)
+
+// proverInfo is the reply of the info method of prover-rpcd.
+type proverInfo struct {
+	Tasks []struct {
+		Options struct {
+			Block uint64 `json:"block"`
+			Rpc   string `json:"rpc"`
+		} `json:"options"`
+		Result *struct {
+			Ok  json.RawMessage `json:"Ok"`
+			Err *string         `json:"Err"`
+		} `json:"result"`
+	} `json:"tasks"`
+}
+
+// RollupProverStatus asks the prover of the rollup for its tasks and
+// compares the latest proven block with the l2 head. Tasks of other rollups
+// in a shared pool are told apart by the l2 rpc they prove against.
+func RollupProverStatus(
+	ctx context.Context,
+	config *L2Config,
+	rollup *types.Rollup,
+) *types.ProverStatus {
+	prover := rollup.Consensus.Prover
+	status := &types.ProverStatus{
+		Name:       rollup.Name,
+		Mode:       prover.Mode,
+		Deployment: types.ProverDeploymentNone,
+	}
+	switch {
+	case prover.Pool != "":
+		status.Deployment = types.ProverDeploymentPool
+		status.Pool = prover.Pool
+		pool, ok := config.ProverConfig.Pools[prover.Pool]
+		if !ok {
+			status.Error = fmt.Sprintf("prover pool %s is not defined in the server config", prover.Pool)
+			return status
+		}
+		status.Url = pool.PublicRpcUrl
+	case prover.Dedicated():
+		status.Deployment = types.ProverDeploymentDedicated
+		status.Url = fmt.Sprintf("http://127.0.0.1:%d", proverPort(rollup))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	var head hexutil.Uint64
+	if err := callRpc(ctx, rollup.SequencerRpcUrl, &head, "eth_blockNumber"); err != nil {
+		status.Error = fmt.Sprintf("failed to get the l2 head, %s", err)
+		return status
+	}
+	status.L2Head = uint64(head)
+	if status.Url == "" {
+		return status
+	}
+
+	info := &proverInfo{}
+	if err := callRpc(ctx, status.Url, info, "info"); err != nil {
+		status.Error = fmt.Sprintf("prover is unreachable, %s", err)
+		return status
+	}
+	status.Online = true
+	l2RpcUrl := fmt.Sprintf(HttpHostnameInDockerPattern, rollup.BasePort)
+	for _, task := range info.Tasks {
+		if status.Deployment == types.ProverDeploymentPool && task.Options.Rpc != l2RpcUrl {
+			continue
+		}
+		switch {
+		case task.Result == nil:
+			status.PendingTasks++
+		case task.Result.Err != nil:
+			status.FailedTasks++
+		case task.Options.Block > status.LatestProvenBlock:
+			status.LatestProvenBlock = task.Options.Block
+		}
+	}
+	if status.L2Head > status.LatestProvenBlock {
+		status.ProofLag = status.L2Head - status.LatestProvenBlock
+	}
+	return status
+}
+
+func callRpc(ctx context.Context, url string, result interface{}, method string, args ...interface{}) error {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.CallContext(ctx, result, method, args...)
+}
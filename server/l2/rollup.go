@@ -3,10 +3,14 @@ package l2
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"os"
 	"path"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/l1"
 	"github.com/g1g2-lab/automation/pkg/db"
 	"github.com/g1g2-lab/automation/types"
 	"github.com/g1g2-lab/automation/util"
@@ -19,13 +23,29 @@ func CreateRollup(
 	request *types.CreateRollupRequest,
 	db *db.LocalFileDatabase,
 ) (*types.Rollup, error) {
+	return CreateRollupFromSpec(ctx, config, request.Spec(), db)
+}
+
+func CreateRollupFromSpec(
+	ctx context.Context,
+	config *L2Config,
+	spec *types.RollupSpec,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
 
 	builder, err := NewBuilder(ctx, config)
 	if err != nil {
 		return nil, err
 	}
 
-	rollup := rollupFromRequest(request)
+	rollups, err := db.GetRollups()
+	if err != nil {
+		return nil, err
+	}
+	rollup, err := rollupFromSpec(spec, config, rollups)
+	if err != nil {
+		return nil, err
+	}
 	rollup.Step = types.RollupDeployOnL1
 	rollup.Status = "deploy to l1"
 	err = db.CreateRollup(rollup)
@@ -33,25 +53,20 @@ func CreateRollup(
 		return nil, err
 	}
 
-	err = createRollupImpl(rollup, builder, db, config)
+	err = createRollupImpl(ctx, rollup, builder, db, config)
 
 	return rollup, err
 }
 
 func createRollupImpl(
+	ctx context.Context,
 	rollup *types.Rollup,
 	builder *RollupBuilder,
 	db *db.LocalFileDatabase,
 	config *L2Config,
 ) error {
-	// render node templates
-	nodeBuildDir := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name, "l2_geth")
-	err := os.MkdirAll(nodeBuildDir, 0755)
-	if err != nil {
-		return err
-	}
 	// step1: deploy l1 rollup && generate l2 genesis
-	err = DeployL1Rollup(rollup, builder, db, config.G1G2Admin)
+	err := DeployL1Rollup(ctx, rollup, builder, db, config)
 	if err != nil {
 		return err
 	} else {
@@ -60,6 +75,44 @@ func createRollupImpl(
 		db.UpdateRollup(rollup)
 	}
 
+	err = RenderRollup(rollup, builder, db)
+	if err != nil {
+		return err
+	}
+	rollup.Step = types.BuildSequencerImage
+	return db.UpdateRollup(rollup)
+}
+
+// RenderRollup renders every artifact of the rollup into its build dir:
+// the execution node, the consensus client and the docker compose file.
+func RenderRollup(
+	rollup *types.Rollup,
+	builder *RollupBuilder,
+	db *db.LocalFileDatabase,
+) error {
+	// the images are pinned before they are rendered, so the artifacts of a
+	// rollup do not change when its record gets the digests
+	resolveDigests(builder, rollup)
+	return RenderRollupTo(rollup, builder, db, path.Join(util.ToAbsolutePath(BuildDir), rollup.Name))
+}
+
+func RenderRollupTo(
+	rollup *types.Rollup,
+	builder *RollupBuilder,
+	db *db.LocalFileDatabase,
+	dockerDir string,
+) error {
+	err := builder.WriteJwtSecret(dockerDir, false)
+	if err != nil {
+		return err
+	}
+
+	// render node templates
+	nodeBuildDir := path.Join(dockerDir, "l2_geth")
+	err = os.MkdirAll(nodeBuildDir, 0755)
+	if err != nil {
+		return err
+	}
 	err = builder.RenderNodeTemplates(rollup, db, nodeBuildDir)
 	if err != nil {
 		return err
@@ -67,19 +120,12 @@ func createRollupImpl(
 
 	//step2: build l2 docker image
 	err = builder.BuildL2(nodeBuildDir, rollup)
-
 	if err != nil {
 		return err
-	} else {
-		rollup.Step = types.BuildSequencerImage
-		db.UpdateRollup(rollup)
-		if err != nil {
-			return err
-		}
 	}
 
 	// step3: build consensus
-	consensusBuildDir := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name, "consensus")
+	consensusBuildDir := path.Join(dockerDir, "consensus")
 	err = os.MkdirAll(consensusBuildDir, 0755)
 	if err != nil {
 		return err
@@ -89,8 +135,12 @@ func createRollupImpl(
 		return err
 	}
 
+	err = builder.RenderRpcLbTemplates(rollup, path.Join(dockerDir, "rpc_lb"))
+	if err != nil {
+		return err
+	}
+
 	// step4: render docker compose
-	dockerDir := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name)
 	err = builder.RenderRollupTemplates(rollup, dockerDir)
 	if err != nil {
 		return err
@@ -108,34 +158,181 @@ func RunRollup(
 		return err
 	}
 	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), rollup.Name)
-	builder.RunRollup(rollupDir)
+	err = builder.RunRollup(rollupDir)
+	if err != nil {
+		return err
+	}
 
-	l2PubRpcUrl := fmt.Sprintf("http://127.0.0.1:%d", config.NodeConfig.BasePort)
-	log15.Info("L2 info: ", "rpc", l2PubRpcUrl)
+	setRpcUrls(rollup)
+	log15.Info("L2 info: ", "rpc", rollup.RpcUrl, "sequencer", rollup.SequencerRpcUrl)
 
 	// waiting l2 running
-	waitingL2Running(l2PubRpcUrl)
+	err = waitRollupRunning(context.Background(), config, rollup)
+	if err != nil {
+		return err
+	}
+
+	if !config.VerifyConfig.Disabled {
+		rollup.Step = types.WaitItOnline
+		rollup.Status = "verifying"
+		err = db.UpdateRollup(rollup)
+		if err != nil {
+			return err
+		}
+		rollup.Verification = VerifyRollup(context.Background(), config, rollup)
+		if !rollup.Verification.Passed {
+			return fmt.Errorf("rollup %s failed verification: %s", rollup.Name, strings.Join(rollup.Verification.Failed(), ", "))
+		}
+	}
 
 	rollup.Step = types.Online
-	rollup.RpcUrl = l2PubRpcUrl
+	rollup.Status = "online"
 	err = db.UpdateRollup(rollup)
 	return err
 }
 
-func waitingL2Running(l2Rpc string) {
-	for {
-		genesisHash, err := util.ExecWrapper(fmt.Sprintf("cast block 0 hash --rpc-url=%s", l2Rpc)).String()
+// VerifyRollupByName verifies a running rollup on demand and records the
+// result. A rollup that failed the verification of its provisioning is
+// marked online once it passes.
+func VerifyRollupByName(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	db *db.LocalFileDatabase,
+) (*types.RollupVerification, error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.Step != types.Online && rollup.Step != types.WaitItOnline {
+		return nil, fmt.Errorf("rollup %s is %s, only running rollups can be verified", name, types.StepName(rollup.Step))
+	}
+	if rollup.RpcUrl == "" {
+		return nil, fmt.Errorf("rollup %s is not running yet", name)
+	}
+	verification := VerifyRollup(ctx, config, rollup)
+	rollup.Verification = verification
+	if verification.Passed && rollup.Step == types.WaitItOnline {
+		rollup.Step = types.Online
+		rollup.Status = "online"
+		rollup.Error = ""
+	}
+	return verification, db.UpdateRollup(rollup)
+}
+
+// resolveDigests records the digests of images the rollup runs without one,
+// pulling the images missing locally. Images that can not be inspected stay
+// unpinned.
+func resolveDigests(builder *RollupBuilder, rollup *types.Rollup) {
+	images := []struct {
+		image  string
+		digest *string
+	}{
+		{rollup.ExecutionImage, &rollup.ExecutionDigest},
+		{rollup.ConsensusImage, &rollup.ConsensusDigest},
+		{rollup.ProverImage, &rollup.ProverDigest},
+	}
+	for _, i := range images {
+		if i.image == "" || *i.digest != "" {
+			continue
+		}
+		digest, err := builder.ImageDigest(i.image)
+		if err != nil {
+			err = builder.PullImage(i.image)
+			if err == nil {
+				digest, err = builder.ImageDigest(i.image)
+			}
+		}
 		if err != nil {
-			log15.Info("get genesis hash error", "err", err)
-			time.Sleep(time.Second * 5)
+			log15.Warn("failed to resolve image digest", "image", i.image, "err", err)
 			continue
 		}
-		log15.Info("L2 is running...", "genesis hash", genesisHash)
-		return
+		*i.digest = digest
 	}
 }
 
-func StopRollupByName(
+func validateReplicas(replicas int) error {
+	if replicas < 0 || replicas > types.MaxReplicas {
+		return fmt.Errorf("replicas must be between 0 and %d, got %d", types.MaxReplicas, replicas)
+	}
+	return nil
+}
+
+// setRpcUrls points RpcUrl at the replica load balancer when the rollup has
+// replicas and at the sequencer otherwise, and sets the url of the explorer.
+func setRpcUrls(rollup *types.Rollup) {
+	rollup.SequencerRpcUrl = fmt.Sprintf("http://127.0.0.1:%d", rollup.BasePort)
+	rollup.RpcUrl = rollup.SequencerRpcUrl
+	if rollup.Replicas > 0 {
+		rollup.RpcUrl = fmt.Sprintf("http://127.0.0.1:%d", rpcLbPort(rollup))
+	}
+	rollup.ExplorerUrl = ""
+	if rollup.Explorer.IsEnabled() {
+		rollup.ExplorerUrl = fmt.Sprintf("http://127.0.0.1:%d", explorerPort(rollup))
+	}
+}
+
+// portRange is the number of host ports from the base port reserved for a
+// rollup, the ports derived from it stay inside.
+const portRange = 10
+
+func rpcLbPort(rollup *types.Rollup) int {
+	return rollup.BasePort + 3
+}
+
+// proverPort is the host port of a dedicated prover, bound to localhost for
+// the status of the server.
+func proverPort(rollup *types.Rollup) int {
+	return rollup.BasePort + 4
+}
+
+// explorerPort is the host port of the explorer.
+func explorerPort(rollup *types.Rollup) int {
+	if rollup.Explorer.Port != 0 {
+		return rollup.Explorer.Port
+	}
+	return rollup.BasePort + 5
+}
+
+// waitingL2Running waits until the node at l2Rpc serves its genesis block,
+// at most timeout.
+func waitingL2Running(ctx context.Context, l2Rpc string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, l2Rpc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	for {
+		genesis, err := client.HeaderByNumber(ctx, big.NewInt(0))
+		if err == nil {
+			log15.Info("L2 is running...", "genesis hash", genesis.Hash())
+			return nil
+		}
+		log15.Info("get genesis hash error", "err", err)
+		if sleepCtx(ctx, time.Second*5) != nil {
+			return fmt.Errorf("l2 node at %s does not serve rpc after %s, %w", l2Rpc, timeout, err)
+		}
+	}
+}
+
+// waitRollupRunning waits until the sequencer and the rpc url of rollup
+// serve rpc.
+func waitRollupRunning(ctx context.Context, config *L2Config, rollup *types.Rollup) error {
+	timeout := config.VerifyConfig.StartTimeout
+	if err := waitingL2Running(ctx, rollup.SequencerRpcUrl, timeout); err != nil {
+		return err
+	}
+	if rollup.RpcUrl == rollup.SequencerRpcUrl {
+		return nil
+	}
+	return waitingL2Running(ctx, rollup.RpcUrl, timeout)
+}
+
+// DeleteRollupByName removes the containers and data volumes of a rollup and
+// deletes its record.
+func DeleteRollupByName(
 	ctx context.Context,
 	config *L2Config,
 	rollupName string,
@@ -154,23 +351,189 @@ func StopRollupByName(
 	return err
 }
 
-func rollupFromRequest(request *types.CreateRollupRequest) *types.Rollup {
-	return &types.Rollup{
-		Name:               request.Name,
-		ChainId:            request.ChainId,
-		L1:                 types.G1G2DockerDevL1,
-		L2FundWallets:      request.L2FundWallets,
-		BeneficiaryAddress: request.BeneficiaryAddress,
+func RollupLogsByName(
+	ctx context.Context,
+	config *L2Config,
+	rollupName string,
+	service string,
+	tail int,
+) (string, error) {
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return "", err
+	}
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), rollupName)
+	return builder.RollupLogs(rollupDir, service, tail)
+}
+
+// ValidateSpec checks the spec merged with the config defaults. Its ports are
+// checked against the other rollups when the rollup is created.
+func ValidateSpec(spec *types.RollupSpec, config *L2Config) error {
+	_, err := rollupFromSpec(spec, config, nil)
+	return err
+}
+
+// rollupFromSpec builds the rollup record of a spec, filling every field the
+// spec leaves empty from the config. A spec without a base port gets the
+// range above the highest one of rollups, and no port may be one that another
+// of rollups uses.
+func rollupFromSpec(spec *types.RollupSpec, config *L2Config, rollups []*types.Rollup) (*types.Rollup, error) {
+	if err := types.ValidateRollupName(spec.Name); err != nil {
+		return nil, err
+	}
+	l1Net, err := l1.ResolveNetwork(spec.L1, &config.L1Dev)
+	if err != nil {
+		return nil, err
+	}
+	executionVersion := spec.Images.Execution
+	if executionVersion == "" {
+		executionVersion = config.DockerImageConfig.ExecutionBase
+	}
+	consensusVersion := spec.Images.Consensus
+	if consensusVersion == "" {
+		consensusVersion = config.DockerImageConfig.ConsensusBase
+	}
+	basePort := spec.Ports.Base
+	if basePort == 0 {
+		basePort = freeBasePort(config, rollups)
+	}
+	consensusDefaults := types.DefaultConsensusSpec
+	consensusDefaults.RelayDbUrl = DockerRelayDBUrl
+	consensus := spec.Consensus.Merge(config.ConsensusConfig).Merge(consensusDefaults)
+	if err := consensus.Validate(); err != nil {
+		return nil, err
+	}
+	proverImage := ""
+	if pool := consensus.Prover.Pool; pool != "" {
+		if _, ok := config.ProverConfig.Pools[pool]; !ok {
+			return nil, fmt.Errorf("consensus prover pool %s is not defined in the server config", pool)
+		}
+	} else if consensus.Prover.Dedicated() {
+		proverVersion := spec.Images.Prover
+		if proverVersion == "" {
+			proverVersion = config.DockerImageConfig.ProverBase
+		}
+		if proverVersion == "" {
+			return nil, fmt.Errorf("a dedicated prover needs images.prover or docker_image.prover_base of the server config")
+		}
+		proverImage = fmt.Sprintf("%s:%s", ProverImageRepo, proverVersion)
+	}
+	node := spec.Node.Merge(config.NodeConfig.Profile).Merge(types.DefaultNodeProfile)
+	if err := node.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateReplicas(spec.Replicas); err != nil {
+		return nil, err
+	}
+	faucet := spec.Faucet.Merge(config.FaucetConfig).Merge(types.DefaultFaucetSpec)
+	if faucet.Enabled == nil {
+		enabled := l1.ProviderKind(l1Net, &config.L1Dev) != l1.ProviderExternal
+		faucet.Enabled = &enabled
+	}
+	if err := faucet.Validate(); err != nil {
+		return nil, err
+	}
+	currency := spec.NativeCurrency.Merge(types.DefaultNativeCurrency)
+	if err := currency.Validate(); err != nil {
+		return nil, err
+	}
+	if err := types.ValidateIcon(spec.Icon); err != nil {
+		return nil, err
+	}
+	explorer := spec.Explorer.Merge(types.ExplorerSpec{
+		Network: spec.Name,
+		Label:   spec.Name,
+		Coin:    currency.Symbol,
+	}).Merge(config.ExplorerConfig).Merge(types.DefaultExplorerSpec)
+	if err := explorer.Validate(); err != nil {
+		return nil, err
+	}
+	faucetAccount := ""
+	if faucet.IsEnabled() {
+		faucetAccount, err = faucetAddress(config, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rollup := &types.Rollup{
+		Name:               spec.Name,
+		ChainId:            spec.ChainId,
+		L1:                 l1Net,
+		L2FundWallets:      spec.L2FundWallets,
+		BeneficiaryAddress: spec.BeneficiaryAddress,
+		ExecutionImage:     fmt.Sprintf("%s:%s", ExecutionImageRepo, executionVersion),
+		ConsensusImage:     fmt.Sprintf("%s:%s", ConsensusImageRepo, consensusVersion),
+		ProverImage:        proverImage,
+		BasePort:           basePort,
+		Consensus:          consensus,
+		Node:               node,
+		Replicas:           spec.Replicas,
+		Faucet:             faucet,
+		FaucetAddress:      faucetAccount,
+		Explorer:           explorer,
+		Icon:               spec.Icon,
+		NativeCurrency:     currency,
+	}
+	if err := checkPorts(rollup, rollups); err != nil {
+		return nil, err
+	}
+	return rollup, nil
+}
+
+// freeBasePort is the base port of the config, or the first one above the
+// port ranges of rollups.
+func freeBasePort(config *L2Config, rollups []*types.Rollup) int {
+	basePort := config.NodeConfig.BasePort
+	for _, r := range rollups {
+		if r.BasePort+portRange > basePort {
+			basePort = r.BasePort + portRange
+		}
+	}
+	return basePort
+}
+
+// reservedPorts are the host ports of a rollup, the range above its base port
+// the other ports are derived from and its explorer port.
+func reservedPorts(rollup *types.Rollup) map[int]bool {
+	ports := map[int]bool{}
+	for port := rollup.BasePort; port < rollup.BasePort+portRange; port++ {
+		ports[port] = true
+	}
+	if rollup.Explorer.IsEnabled() {
+		ports[explorerPort(rollup)] = true
 	}
+	return ports
+}
+
+// checkPorts refuses ports of rollup that another of rollups reserves. Stopped
+// and archived rollups keep theirs, they bind them again when started.
+func checkPorts(rollup *types.Rollup, rollups []*types.Rollup) error {
+	ports := reservedPorts(rollup)
+	for _, r := range rollups {
+		if r.Name == rollup.Name {
+			continue
+		}
+		for port := range reservedPorts(r) {
+			if ports[port] {
+				return fmt.Errorf("port %d of rollup %s is used by rollup %s", port, rollup.Name, r.Name)
+			}
+		}
+	}
+	return nil
 }
 
 func DeployL1Rollup(
+	ctx context.Context,
 	rollup *types.Rollup,
 	builder *RollupBuilder,
 	db *db.LocalFileDatabase,
-	g1g2Admin G1G2Admin,
+	config *L2Config,
 ) error {
-	err := builder.DeployL1Rollup(rollup, g1g2Admin)
+	err := prepareL1(ctx, config, rollup)
+	if err != nil {
+		return err
+	}
+	err = builder.DeployL1Rollup(rollup, config.G1G2Admin)
 	if err != nil {
 		return err
 	}
@@ -180,17 +543,71 @@ func DeployL1Rollup(
 	if err != nil {
 		return err
 	}
-	rollup.L1Rollup = l1ContractAddresses.L1Proxies.L1Rollup
-	rollup.L1Bridge = l1ContractAddresses.L1Proxies.CrossChainChannel
-	rollup.L1Escrow = l1ContractAddresses.L1Proxies.L1Escrow
-	rollup.L1AddressManager = l1ContractAddresses.L1Proxies.AddressManager
+	setContractAddresses(rollup, &l1ContractAddresses.L1Proxies)
+	rollup.ContractsVersion = l1ContractAddresses.Version
+
+	rollup.CreatedAt = time.Now()
+	err = db.UpdateRollup(rollup)
+	return err
+}
+
+// NewL1Provider returns the provider of an l1 picked by its network config.
+func NewL1Provider(config *L2Config, net types.L1Net) (l1.Provider, error) {
+	return l1.NewProvider(net, &config.L1Dev, config.TemplateConfig.OverrideDir, config.G1G2Admin.L1AdminPK)
+}
+
+// prepareL1 starts the l1 of rollup unless it runs, checks its chain id and
+// funds the admin deploying the rollup contracts when it has no balance.
+func prepareL1(ctx context.Context, config *L2Config, rollup *types.Rollup) error {
+	provider, err := NewL1Provider(config, rollup.L1)
+	if err != nil {
+		return err
+	}
+	if err := provider.Start(ctx); err != nil {
+		return err
+	}
+	chainId, err := provider.ChainId(ctx)
+	if err != nil {
+		return err
+	}
+	if chainId.Int64() != int64(rollup.L1.ChainId) {
+		return fmt.Errorf("l1 %s serves chain id %s, the rollup expects %d", rollup.L1.Name, chainId, rollup.L1.ChainId)
+	}
+	admin, err := util.PrivateKeyToAddress(strings.TrimPrefix(config.G1G2Admin.L1AdminPK, "0x"))
+	if err != nil {
+		return err
+	}
+	client, err := ethclient.DialContext(ctx, rollup.L1.PublicRpcUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	balance, err := client.BalanceAt(ctx, admin, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Sign() > 0 {
+		return nil
+	}
+	amount, ok := new(big.Int).SetString(config.L1Dev.MinerPremintWei, 10)
+	if !ok || amount.Sign() <= 0 {
+		return fmt.Errorf("l1_dev miner_premint_wei must be a positive amount in wei, got %q", config.L1Dev.MinerPremintWei)
+	}
+	if err := provider.Fund(ctx, admin, amount); err != nil {
+		return fmt.Errorf("the l1 admin %s has no funds on l1 %s, %w", admin, rollup.L1.Name, err)
+	}
+	log15.Info("funded l1 admin", "l1", rollup.L1.Name, "admin", admin, "amount", amount)
+	return nil
+}
+
+func setContractAddresses(rollup *types.Rollup, l1Proxies *types.L1Proxies) {
+	rollup.L1Rollup = l1Proxies.L1Rollup
+	rollup.L1Bridge = l1Proxies.CrossChainChannel
+	rollup.L1Escrow = l1Proxies.L1Escrow
+	rollup.L1AddressManager = l1Proxies.AddressManager
 
 	rollup.L2Rollup = types.L2RollupAddr
 	rollup.L2Bridge = types.L2BridgeAddr
 	rollup.L2Escrow = types.L2EscrowAddr
 	rollup.L2AddressManager = types.L2AddressManagerAddr
-
-	rollup.CreatedAt = time.Now()
-	err = db.UpdateRollup(rollup)
-	return err
 }
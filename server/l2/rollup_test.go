@@ -0,0 +1,44 @@
+package l2
+
+import (
+	"testing"
+
+	"github.com/g1g2-lab/automation/types"
+)
+
+func TestRollupsGetTheirOwnPorts(t *testing.T) {
+	config := &L2Config{NodeConfig: NodeConfig{BasePort: 10545}}
+	enabled := true
+	rollups := []*types.Rollup{}
+	if port := freeBasePort(config, rollups); port != 10545 {
+		t.Fatalf("first rollup gets base port %d, want the one of the config", port)
+	}
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		rollup := &types.Rollup{
+			Name:     name,
+			BasePort: freeBasePort(config, rollups),
+			Explorer: types.ExplorerSpec{Enabled: &enabled},
+		}
+		if err := checkPorts(rollup, rollups); err != nil {
+			t.Fatal(err)
+		}
+		rollups = append(rollups, rollup)
+	}
+	if port := rollups[2].BasePort; port != 10545+2*portRange {
+		t.Fatalf("third rollup gets base port %d", port)
+	}
+
+	taken := []*types.Rollup{
+		{Name: "delta", BasePort: 10547},
+		{Name: "delta", BasePort: 11000, Explorer: types.ExplorerSpec{Enabled: &enabled, Port: 10550}},
+	}
+	for _, rollup := range taken {
+		if err := checkPorts(rollup, rollups); err == nil {
+			t.Fatalf("rollup with base port %d and explorer port %d shares ports", rollup.BasePort, explorerPort(rollup))
+		}
+	}
+	// a rollup applied again keeps its own ports
+	if err := checkPorts(rollups[0], rollups); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,195 @@
+package l2
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+var (
+	// UpgradeHealthTimeout bounds how long an upgraded node may take to serve
+	// rpc again, UpgradeSettleTime how long the upgraded containers must keep
+	// running before the upgrade counts as healthy.
+	UpgradeHealthTimeout = time.Minute * 3
+	UpgradeSettleTime    = time.Second * 20
+)
+
+// UpgradeRollupContracts upgrades the l1 and l2 rollup contracts of a
+// running rollup to version. Like the upgrade_rollup_contracts task it only
+// moves forward, and every attempt is appended to the upgrade history.
+func UpgradeRollupContracts(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	version int,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.Step != types.Online {
+		return nil, fmt.Errorf("rollup %s must be online to upgrade its contracts", name)
+	}
+	contracts, err := db.GetRollupContracts(rollup.L1.ChainId, rollup.ChainId)
+	if err != nil {
+		return nil, err
+	}
+	current := contracts.Version
+	if version <= current {
+		return nil, fmt.Errorf("rollup %s contracts are at version %d, the new version must be greater", name, current)
+	}
+
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	upgrade := types.ContractUpgrade{From: current, To: version, At: time.Now()}
+	err = builder.UpgradeRollupContracts(rollup, config.G1G2Admin, version)
+	if err == nil {
+		contracts, err = db.GetRollupContracts(rollup.L1.ChainId, rollup.ChainId)
+	}
+	if err == nil && contracts.Version != version {
+		err = fmt.Errorf("contracts are at version %d after the upgrade to %d", contracts.Version, version)
+	}
+	if err != nil {
+		upgrade.Error = err.Error()
+	} else {
+		rollup.ContractsVersion = version
+	}
+	rollup.Upgrades = append(rollup.Upgrades, upgrade)
+	if dbErr := db.UpdateRollup(rollup); dbErr != nil && err == nil {
+		err = dbErr
+	}
+	return rollup, err
+}
+
+// UpgradeRollupImage moves the execution or consensus client of a running
+// rollup to version. Only the services built from that image are rebuilt and
+// restarted, their data volume is kept. When the upgraded services are not
+// healthy the previous image is restored.
+func UpgradeRollupImage(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	image string,
+	version string,
+	db *db.LocalFileDatabase,
+) (*types.Rollup, error) {
+	current, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if current.Step != types.Online {
+		return nil, fmt.Errorf("rollup %s must be online to upgrade its images", name)
+	}
+	updated := *current
+	var services []string
+	switch image {
+	case types.ImageExecution:
+		setImage(&updated.ExecutionImage, &updated.ExecutionDigest, fmt.Sprintf("%s:%s", ExecutionImageRepo, version))
+		services = append(services, SequencerService)
+		for _, index := range replicaIndexes(current) {
+			services = append(services, replicaService(index))
+		}
+	case types.ImageConsensus:
+		setImage(&updated.ConsensusImage, &updated.ConsensusDigest, fmt.Sprintf("%s:%s", ConsensusImageRepo, version))
+		services = append(services, "consensus")
+	default:
+		return nil, fmt.Errorf("image must be %s or %s, got %q", types.ImageExecution, types.ImageConsensus, image)
+	}
+	if updated.ExecutionImage == current.ExecutionImage && updated.ConsensusImage == current.ConsensusImage {
+		return nil, fmt.Errorf("rollup %s already runs %s %s", name, image, version)
+	}
+
+	builder, err := NewBuilder(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	rollupDir := path.Join(util.ToAbsolutePath(BuildDir), name)
+	var head hexutil.Uint64
+	if err := callRpc(ctx, current.SequencerRpcUrl, &head, "eth_blockNumber"); err != nil {
+		return nil, fmt.Errorf("rollup %s is not healthy before the upgrade, %w", name, err)
+	}
+
+	err = RenderRollup(&updated, builder, db)
+	if err == nil {
+		err = builder.UpdateRollup(rollupDir, services)
+	}
+	if err == nil {
+		err = checkUpgradeHealth(ctx, builder, &updated, rollupDir, services, uint64(head))
+	}
+	if err != nil {
+		log15.Error("image upgrade failed, rolling back", "name", name, "image", image, "version", version, "err", err)
+		rollbackErr := RenderRollup(current, builder, db)
+		if rollbackErr == nil {
+			rollbackErr = builder.UpdateRollup(rollupDir, services)
+		}
+		if rollbackErr != nil {
+			return nil, fmt.Errorf("upgrade of %s to %s failed, %w, and the rollback failed, %s", image, version, err, rollbackErr)
+		}
+		return nil, fmt.Errorf("upgrade of %s to %s failed and was rolled back, %w", image, version, err)
+	}
+
+	err = db.UpdateRollup(&updated)
+	return &updated, err
+}
+
+// checkUpgradeHealth waits until the rpc of the rollup serves a head not
+// behind the one before the upgrade, then checks the upgraded services are
+// still running after UpgradeSettleTime.
+func checkUpgradeHealth(
+	ctx context.Context,
+	builder *RollupBuilder,
+	rollup *types.Rollup,
+	dir string,
+	services []string,
+	head uint64,
+) error {
+	deadline := time.Now().Add(UpgradeHealthTimeout)
+	for {
+		err := checkRpcHead(ctx, rollup.SequencerRpcUrl, head)
+		if err == nil {
+			err = checkRpcHead(ctx, rollup.RpcUrl, 0)
+		}
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("rpc is not healthy after %s, %w", UpgradeHealthTimeout, err)
+		}
+		time.Sleep(time.Second * 5)
+	}
+
+	time.Sleep(UpgradeSettleTime)
+	running, err := builder.RunningServices(dir)
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		if !running[service] {
+			return fmt.Errorf("service %s stopped after the upgrade", service)
+		}
+	}
+	return checkRpcHead(ctx, rollup.SequencerRpcUrl, head)
+}
+
+func checkRpcHead(ctx context.Context, url string, min uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	var head hexutil.Uint64
+	if err := callRpc(ctx, url, &head, "eth_blockNumber"); err != nil {
+		return err
+	}
+	if uint64(head) < min {
+		return fmt.Errorf("head %d is behind %d", head, min)
+	}
+	return nil
+}
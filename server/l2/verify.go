@@ -0,0 +1,390 @@
+package l2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/pkg/bridge"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+const (
+	verifyPollInterval = time.Second * 2
+	// verifyBridgeGas covers the funding of the deposit account and the gas
+	// the harness gives both legs.
+	verifyBridgeGas = 2*300000 + 21000
+)
+
+// verifyKey is the key of the account the checks of rollup name send from,
+// so they do not race the nonces of the admin key the consensus client uses.
+func verifyKey(config *L2Config, name string) (*ecdsa.PrivateKey, error) {
+	return derivedKey(config, "g1g2 verify", name)
+}
+
+// VerifyRollup runs the checks of a rollup one after the other, each bounded
+// by its timeout of config. The proposal and proof checks look at the
+// transactions of the admin to L1Rollup from the l1 head at the start on,
+// so they do not pass on blocks proposed before.
+func VerifyRollup(
+	ctx context.Context,
+	config *L2Config,
+	rollup *types.Rollup,
+) *types.RollupVerification {
+	verifyConfig := config.VerifyConfig.WithDefaults()
+	verification := &types.RollupVerification{
+		Name:      rollup.Name,
+		StartedAt: time.Now().UTC(),
+	}
+	v := &verifier{
+		config:   config,
+		rollup:   rollup,
+		l1Rollup: common.HexToAddress(rollup.L1Rollup),
+	}
+	v.fund, _ = new(big.Int).SetString(verifyConfig.FundWei, 10)
+	checks := []struct {
+		name    string
+		timeout time.Duration
+		run     func(ctx context.Context) (string, error)
+	}{
+		{types.VerifyBlocks, verifyConfig.BlocksTimeout, v.blocks},
+		{types.VerifyTransaction, verifyConfig.TransactionTimeout, v.transaction},
+		{types.VerifyProposal, verifyConfig.ProposalTimeout, v.proposal},
+		{types.VerifyProof, verifyConfig.ProofTimeout, v.proof},
+		{types.VerifyBridge, verifyConfig.BridgeTimeout, func(ctx context.Context) (string, error) {
+			return v.bridge(ctx, verifyConfig)
+		}},
+	}
+
+	err := v.dial(ctx)
+	if err != nil {
+		for _, check := range checks {
+			verification.Checks = append(verification.Checks, types.VerifyCheck{
+				Name:   check.name,
+				Result: types.CheckFailed,
+				Detail: err.Error(),
+			})
+		}
+		return verification
+	}
+	defer v.close()
+
+	verification.Passed = true
+	for _, check := range checks {
+		log15.Info("verify rollup", "name", rollup.Name, "check", check.name)
+		checkCtx, cancel := context.WithTimeout(ctx, check.timeout)
+		start := time.Now()
+		detail, err := check.run(checkCtx)
+		cancel()
+		result := types.VerifyCheck{
+			Name:     check.name,
+			Result:   types.CheckPassed,
+			Detail:   detail,
+			Duration: time.Since(start),
+		}
+		switch {
+		case err == errSkipped:
+			result.Result = types.CheckSkipped
+		case err != nil:
+			if checkCtx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("timed out after %s, %w", check.timeout, err)
+			}
+			result.Result = types.CheckFailed
+			result.Detail = err.Error()
+			verification.Passed = false
+			log15.Warn("rollup check failed", "name", rollup.Name, "check", check.name, "err", err)
+		}
+		verification.Checks = append(verification.Checks, result)
+	}
+	return verification
+}
+
+var errSkipped = fmt.Errorf("skipped")
+
+type verifier struct {
+	config   *L2Config
+	rollup   *types.Rollup
+	l1Rollup common.Address
+	admin    common.Address
+	adminKey *ecdsa.PrivateKey
+	// key is the account the transaction and bridge checks send from, the
+	// admin tops it up to fund.
+	key     *ecdsa.PrivateKey
+	account common.Address
+	fund    *big.Int
+	l1      *ethclient.Client
+	l2      *ethclient.Client
+	// l1Start is the l1 head when the verification started.
+	l1Start uint64
+	// rollupAbi tells proposals from proofs, it is nil when the contracts
+	// repo has no artifact of L1Rollup.
+	rollupAbi *abi.ABI
+}
+
+func (v *verifier) dial(ctx context.Context) error {
+	var err error
+	v.adminKey, err = crypto.HexToECDSA(strings.TrimPrefix(v.config.G1G2Admin.L1AdminPK, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid admin key, %w", err)
+	}
+	v.admin = crypto.PubkeyToAddress(v.adminKey.PublicKey)
+	v.key, err = verifyKey(v.config, v.rollup.Name)
+	if err != nil {
+		return err
+	}
+	v.account = crypto.PubkeyToAddress(v.key.PublicKey)
+	v.l1, err = ethclient.DialContext(ctx, v.rollup.L1.PublicRpcUrl)
+	if err != nil {
+		return err
+	}
+	v.l2, err = ethclient.DialContext(ctx, v.rollup.RpcUrl)
+	if err != nil {
+		v.l1.Close()
+		return err
+	}
+	v.l1Start, err = v.l1.BlockNumber(ctx)
+	if err != nil {
+		v.close()
+		return fmt.Errorf("failed to get the l1 head, %w", err)
+	}
+	v.rollupAbi, err = loadL1RollupAbi(v.config)
+	if err != nil {
+		log15.Warn("can not tell proposals from proofs", "err", err)
+	}
+	return nil
+}
+
+func (v *verifier) close() {
+	v.l1.Close()
+	v.l2.Close()
+}
+
+func loadL1RollupAbi(config *L2Config) (*abi.ABI, error) {
+	builder, err := NewBuilder(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	artifact, err := loadArtifact(builder, "L1Rollup")
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(artifact.Abi)))
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// blocks waits for the l2 head to advance.
+func (v *verifier) blocks(ctx context.Context) (string, error) {
+	start, err := v.l2.BlockNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+	for {
+		head, err := v.l2.BlockNumber(ctx)
+		if err == nil && head > start {
+			return fmt.Sprintf("head advanced from %d to %d", start, head), nil
+		}
+		if err := sleepCtx(ctx, verifyPollInterval); err != nil {
+			return "", fmt.Errorf("head stuck at %d", start)
+		}
+	}
+}
+
+// transaction sends a transfer of nothing from the verify account to itself
+// on l2 and waits for it to be included.
+func (v *verifier) transaction(ctx context.Context) (string, error) {
+	gasPrice, err := v.l2.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := v.topUp(ctx, v.l2, new(big.Int).Mul(gasPrice, big.NewInt(21000))); err != nil {
+		return "", err
+	}
+	nonce, err := v.l2.PendingNonceAt(ctx, v.account)
+	if err != nil {
+		return "", err
+	}
+	tx, err := util.SendTransfer(ctx, v.l2, v.key, nonce, v.account, big.NewInt(0))
+	if err != nil {
+		return "", err
+	}
+	receipt, err := util.WaitReceipt(ctx, v.l2, tx.Hash())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("transaction %s included in block %d", tx.Hash(), receipt.BlockNumber), nil
+}
+
+// proposal waits for a successful proposal of the admin to L1Rollup. Any
+// call counts when the abi of L1Rollup is unknown.
+func (v *verifier) proposal(ctx context.Context) (string, error) {
+	if !v.rollup.Consensus.HasRole(types.RolePropose) {
+		return "the consensus client does not propose", errSkipped
+	}
+	return v.waitRollupCall(ctx, "propose")
+}
+
+// proof waits for a successful proof of the admin to L1Rollup.
+func (v *verifier) proof(ctx context.Context) (string, error) {
+	if !v.rollup.Consensus.HasRole(types.RoleProve) {
+		return "the consensus client does not prove", errSkipped
+	}
+	if v.rollupAbi == nil {
+		return "the abi of L1Rollup is unknown, proofs can not be told from proposals", errSkipped
+	}
+	return v.waitRollupCall(ctx, "prove")
+}
+
+func (v *verifier) waitRollupCall(ctx context.Context, method string) (string, error) {
+	signer := ethtypes.LatestSignerForChainID(big.NewInt(int64(v.rollup.L1.ChainId)))
+	next := v.l1Start
+	for {
+		head, err := v.l1.BlockNumber(ctx)
+		for ; err == nil && next <= head; next++ {
+			var block *ethtypes.Block
+			block, err = v.l1.BlockByNumber(ctx, new(big.Int).SetUint64(next))
+			if err != nil {
+				break
+			}
+			for _, tx := range block.Transactions() {
+				name, ok := v.rollupCall(signer, tx)
+				if !ok || (v.rollupAbi != nil && !strings.Contains(strings.ToLower(name), method)) {
+					continue
+				}
+				receipt, err := v.l1.TransactionReceipt(ctx, tx.Hash())
+				if err != nil || receipt.Status != ethtypes.ReceiptStatusSuccessful {
+					continue
+				}
+				return fmt.Sprintf("%s %s in l1 block %d", name, tx.Hash(), next), nil
+			}
+		}
+		if err := sleepCtx(ctx, verifyPollInterval); err != nil {
+			return "", fmt.Errorf("no %s call to L1Rollup %s since l1 block %d", method, v.l1Rollup, v.l1Start)
+		}
+	}
+}
+
+// rollupCall returns the method a transaction of the admin calls on
+// L1Rollup.
+func (v *verifier) rollupCall(signer ethtypes.Signer, tx *ethtypes.Transaction) (string, bool) {
+	if tx.To() == nil || *tx.To() != v.l1Rollup || len(tx.Data()) < 4 {
+		return "", false
+	}
+	from, err := ethtypes.Sender(signer, tx)
+	if err != nil || from != v.admin {
+		return "", false
+	}
+	if v.rollupAbi == nil {
+		return fmt.Sprintf("%x", tx.Data()[:4]), true
+	}
+	method, err := v.rollupAbi.MethodById(tx.Data()[:4])
+	if err != nil {
+		return "", false
+	}
+	return method.Name, true
+}
+
+// bridge deposits from the verify account on l1 and waits for the relayer
+// to deliver it on l2.
+func (v *verifier) bridge(ctx context.Context, verifyConfig VerifyConfig) (string, error) {
+	if !v.rollup.Consensus.HasRole(types.RoleRelay) {
+		return "the consensus client does not relay", errSkipped
+	}
+	amount, _ := new(big.Int).SetString(verifyConfig.DepositWei, 10)
+	fee, _ := new(big.Int).SetString(verifyConfig.RelayFeeWei, 10)
+	gasPrice, err := v.l1.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", err
+	}
+	need := new(big.Int).Add(amount, fee)
+	need.Add(need, new(big.Int).Mul(gasPrice, big.NewInt(verifyBridgeGas)))
+	if err := v.topUp(ctx, v.l1, need); err != nil {
+		return "", err
+	}
+	timeout := verifyConfig.BridgeTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	harness, err := bridge.New(ctx, bridge.Config{
+		L1RpcUrl:    v.rollup.L1.PublicRpcUrl,
+		L2RpcUrl:    v.rollup.RpcUrl,
+		L1Escrow:    common.HexToAddress(v.rollup.L1Escrow),
+		L2Escrow:    common.HexToAddress(v.rollup.L2Escrow),
+		Key:         hexutil.Encode(crypto.FromECDSA(v.key)),
+		Mode:        bridge.ModeDeposit,
+		Amount:      amount,
+		RelayFee:    fee,
+		Transfers:   1,
+		Concurrency: 1,
+		Timeout:     timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	report, err := harness.Run(ctx)
+	if err != nil {
+		return "", err
+	}
+	leg := report.Legs[bridge.ModeDeposit]
+	if leg != nil && leg.Delivered > 0 {
+		return fmt.Sprintf("deposit of %s wei delivered in %s", amount, leg.Finalization.Max.Round(time.Millisecond)), nil
+	}
+	// the funding of the deposit account fails in its own leg
+	for kind, leg := range report.Legs {
+		for e := range leg.Errors {
+			return "", fmt.Errorf("deposit not delivered, %s: %s", kind, e)
+		}
+	}
+	return "", fmt.Errorf("deposit not delivered")
+}
+
+// topUp sends fund, or need when it is more, from the admin to the verify
+// account when it holds less than need on the chain of client.
+func (v *verifier) topUp(ctx context.Context, client *ethclient.Client, need *big.Int) error {
+	balance, err := client.BalanceAt(ctx, v.account, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(need) >= 0 {
+		return nil
+	}
+	nonce, err := client.PendingNonceAt(ctx, v.admin)
+	if err != nil {
+		return err
+	}
+	amount := v.fund
+	if amount.Cmp(need) < 0 {
+		amount = need
+	}
+	tx, err := util.SendTransfer(ctx, client, v.adminKey, nonce, v.account, amount)
+	if err != nil {
+		return fmt.Errorf("failed to fund the verify account %s, %w", v.account, err)
+	}
+	if _, err := util.WaitReceipt(ctx, client, tx.Hash()); err != nil {
+		return fmt.Errorf("failed to fund the verify account %s, %w", v.account, err)
+	}
+	log15.Info("funded verify account", "name", v.rollup.Name, "account", v.account, "amount", amount, "tx", tx.Hash())
+	return nil
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
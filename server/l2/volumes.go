@@ -0,0 +1,62 @@
+package l2
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// VolumeRuntime copies volumes to and from tar streams. Backups only touch
+// volumes through it, so they can run against something other than docker.
+type VolumeRuntime interface {
+	VolumeExists(name string) (bool, error)
+	ExportVolume(name string, w io.Writer) error
+	ImportVolume(name string, r io.Reader) error
+	RemoveVolume(name string) error
+}
+
+// DockerVolumes copies docker volumes with tar in a throwaway container.
+type DockerVolumes struct {
+	Image string
+}
+
+func NewDockerVolumes() *DockerVolumes {
+	return &DockerVolumes{Image: "alpine:3.18"}
+}
+
+func (d *DockerVolumes) VolumeExists(name string) (bool, error) {
+	err := exec.Command("docker", "volume", "inspect", name).Run()
+	if _, ok := err.(*exec.ExitError); ok {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *DockerVolumes) ExportVolume(name string, w io.Writer) error {
+	return d.run(nil, w, "run", "--rm", "-v", name+":/volume:ro", d.Image, "tar", "cf", "-", "-C", "/volume", ".")
+}
+
+func (d *DockerVolumes) ImportVolume(name string, r io.Reader) error {
+	if err := d.run(nil, io.Discard, "volume", "create", name); err != nil {
+		return err
+	}
+	return d.run(r, io.Discard, "run", "--rm", "-i", "-v", name+":/volume", d.Image, "tar", "xf", "-", "-C", "/volume")
+}
+
+func (d *DockerVolumes) RemoveVolume(name string) error {
+	return d.run(nil, io.Discard, "volume", "rm", name)
+}
+
+func (d *DockerVolumes) run(stdin io.Reader, stdout io.Writer, args ...string) error {
+	cmd := exec.Command("docker", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker %s: %w, %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
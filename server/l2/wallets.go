@@ -0,0 +1,183 @@
+package l2
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/pkg/db"
+	"github.com/g1g2-lab/automation/types"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+// fundTimeout bounds the transfers of FundWallets until they are included.
+const fundTimeout = time.Minute * 2
+
+// FundWallets sends each wallet its amount from the rollup admin on l2 and
+// records the transfers in the wallet list of the rollup, adding the wallets
+// it did not list yet. The transfers are recorded once sent, so a transfer
+// that is not included in time is still listed with its hash. Callers hold
+// the lock of the rollup, see Manager.
+func FundWallets(
+	ctx context.Context,
+	config *L2Config,
+	name string,
+	wallets []types.L2FundWallets,
+	db *db.LocalFileDatabase,
+) ([]types.WalletTransfer, error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.Step != types.Online {
+		return nil, fmt.Errorf("rollup %s is %s, only online rollups can fund wallets", name, types.StepName(rollup.Step))
+	}
+	total := new(big.Int)
+	amounts := make([]*big.Int, len(wallets))
+	seen := map[common.Address]bool{}
+	for i, w := range wallets {
+		if !common.IsHexAddress(w.WalletAddress) {
+			return nil, fmt.Errorf("invalid wallet address %q", w.WalletAddress)
+		}
+		address := common.HexToAddress(w.WalletAddress)
+		if seen[address] {
+			return nil, fmt.Errorf("wallet %s is listed twice", address)
+		}
+		seen[address] = true
+		amount, ok := new(big.Int).SetString(w.AmountInWei, 10)
+		if !ok || amount.Sign() <= 0 {
+			return nil, fmt.Errorf("amount of wallet %s must be a positive amount in wei, got %q", address, w.AmountInWei)
+		}
+		amounts[i] = amount
+		total.Add(total, amount)
+	}
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(config.G1G2Admin.L1AdminPK, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin key, %w", err)
+	}
+	admin := crypto.PubkeyToAddress(key.PublicKey)
+	ctx, cancel := context.WithTimeout(ctx, fundTimeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, rollup.RpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := client.BalanceAt(ctx, admin, nil)
+	if err != nil {
+		return nil, err
+	}
+	cost := new(big.Int).Mul(gasPrice, big.NewInt(int64(21000*len(wallets))))
+	cost.Add(cost, total)
+	if balance.Cmp(cost) < 0 {
+		return nil, fmt.Errorf("the rollup admin %s has %s wei on l2, the transfers need %s", admin, balance, cost)
+	}
+	nonce, err := client.PendingNonceAt(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []types.WalletTransfer{}
+	txs := []*ethtypes.Transaction{}
+	var sendErr error
+	for i, w := range wallets {
+		tx, err := util.SendTransfer(ctx, client, key, nonce, common.HexToAddress(w.WalletAddress), amounts[i])
+		if err != nil {
+			sendErr = fmt.Errorf("failed to fund wallet %s, %w", w.WalletAddress, err)
+			break
+		}
+		nonce++
+		txs = append(txs, tx)
+		transfers = append(transfers, types.WalletTransfer{
+			Address:   common.HexToAddress(w.WalletAddress).Hex(),
+			AmountWei: amounts[i].String(),
+			TxHash:    tx.Hash().Hex(),
+			SentAt:    time.Now().UTC(),
+		})
+		log15.Info("fund wallet", "rollup", name, "wallet", w.WalletAddress, "amount", amounts[i], "tx", tx.Hash())
+	}
+	// the record may have changed while the transfers were sent
+	latest, err := db.GetRollupByName(name)
+	if err != nil {
+		return transfers, err
+	}
+	recordTransfers(latest, transfers)
+	if err := db.UpdateRollup(latest); err != nil {
+		return transfers, err
+	}
+	if sendErr != nil {
+		return transfers, sendErr
+	}
+
+	for _, tx := range txs {
+		if _, err := util.WaitReceipt(ctx, client, tx.Hash()); err != nil {
+			return transfers, fmt.Errorf("transfer to %s failed, %w", tx.To(), err)
+		}
+	}
+	return transfers, nil
+}
+
+// recordTransfers adds the transfers to the wallets of the rollup.
+func recordTransfers(rollup *types.Rollup, transfers []types.WalletTransfer) {
+	for _, t := range transfers {
+		found := false
+		for i := range rollup.L2FundWallets {
+			w := &rollup.L2FundWallets[i]
+			if common.IsHexAddress(w.WalletAddress) && common.HexToAddress(w.WalletAddress).Hex() == t.Address {
+				w.Transfers = append(w.Transfers, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			rollup.L2FundWallets = append(rollup.L2FundWallets, types.L2FundWallets{
+				WalletAddress: t.Address,
+				Transfers:     []types.WalletTransfer{t},
+			})
+		}
+	}
+}
+
+// WalletBalances returns the funded wallets of a rollup with their balances
+// on l2.
+func WalletBalances(
+	ctx context.Context,
+	name string,
+	db *db.LocalFileDatabase,
+) ([]types.WalletBalance, error) {
+	rollup, err := db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.Step != types.Online {
+		return nil, fmt.Errorf("rollup %s is %s, only online rollups report balances", name, types.StepName(rollup.Step))
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, rollup.RpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	balances := []types.WalletBalance{}
+	for _, w := range rollup.L2FundWallets {
+		balance, err := client.BalanceAt(ctx, common.HexToAddress(w.WalletAddress), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the balance of %s, %w", w.WalletAddress, err)
+		}
+		balances = append(balances, types.WalletBalance{L2FundWallets: w, BalanceWei: balance.String()})
+	}
+	return balances, nil
+}
@@ -0,0 +1,453 @@
+// Package bridge sends eth through the escrows of a rollup and measures how
+// long the relayer of the consensus client takes to deliver each message.
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/pkg/stats"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+// modes of a run
+const (
+	ModeDeposit   = "deposit"
+	ModeWithdraw  = "withdraw"
+	ModeRoundTrip = "roundtrip"
+)
+
+// pollInterval bounds the precision of the measured durations.
+var pollInterval = time.Second
+
+type Config struct {
+	L1RpcUrl string
+	L2RpcUrl string
+	L1Escrow common.Address
+	L2Escrow common.Address
+	// Key is the hex private key funding the accounts of the transfers, on
+	// l1 for deposits and round trips, on l2 for withdrawals.
+	Key  string
+	Mode string
+	// Amount is the wei deposited, or withdrawn in withdraw mode. A round
+	// trip withdraws what is left after the fee and gas of the withdrawal.
+	Amount   *big.Int
+	RelayFee *big.Int
+	// Transfers run Concurrency at a time, each from its own account.
+	Transfers   int
+	Concurrency int
+	// Timeout is how long a message may take to be delivered.
+	Timeout time.Duration
+}
+
+func (c *Config) Validate() error {
+	if c.L1RpcUrl == "" || c.L2RpcUrl == "" {
+		return fmt.Errorf("l1 and l2 rpc urls are required")
+	}
+	if c.L1Escrow == (common.Address{}) || c.L2Escrow == (common.Address{}) {
+		return fmt.Errorf("l1 and l2 escrow addresses are required")
+	}
+	if c.Key == "" {
+		return fmt.Errorf("funder key is required")
+	}
+	switch c.Mode {
+	case ModeDeposit, ModeWithdraw, ModeRoundTrip:
+	default:
+		return fmt.Errorf("mode must be %s, %s or %s, got %q", ModeDeposit, ModeWithdraw, ModeRoundTrip, c.Mode)
+	}
+	if c.Amount == nil || c.Amount.Sign() <= 0 || c.RelayFee == nil || c.RelayFee.Sign() < 0 {
+		return fmt.Errorf("amount must be positive and relay fee non-negative")
+	}
+	if c.Transfers <= 0 || c.Concurrency <= 0 || c.Timeout <= 0 {
+		return fmt.Errorf("transfers, concurrency and timeout must be positive")
+	}
+	return nil
+}
+
+// Harness runs the transfers of a Config.
+type Harness struct {
+	cfg    Config
+	l1     *chain
+	l2     *chain
+	funder *ecdsa.PrivateKey
+
+	mu     sync.Mutex
+	report *Report
+}
+
+func New(ctx context.Context, cfg Config) (*Harness, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(cfg.Key, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid funder key, %w", err)
+	}
+	l1, err := dialChain(ctx, "l1", cfg.L1RpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	l2, err := dialChain(ctx, "l2", cfg.L2RpcUrl)
+	if err != nil {
+		l1.client.Close()
+		return nil, err
+	}
+	return &Harness{
+		cfg:    cfg,
+		l1:     l1,
+		l2:     l2,
+		funder: key,
+		report: &Report{Mode: cfg.Mode, Transfers: cfg.Transfers, Concurrency: cfg.Concurrency},
+	}, nil
+}
+
+// Run sends the transfers and waits for every message to be delivered or
+// to time out.
+func (h *Harness) Run(ctx context.Context) (*Report, error) {
+	defer h.l1.client.Close()
+	defer h.l2.client.Close()
+	funding := h.l1
+	if h.cfg.Mode == ModeWithdraw {
+		funding = h.l2
+	}
+	funderNonce, err := funding.client.PendingNonceAt(ctx, crypto.PubkeyToAddress(h.funder.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	h.report.Start = time.Now()
+	transfers := make(chan int)
+	var wg sync.WaitGroup
+	var fundMu sync.Mutex
+	for w := 0; w < h.cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range transfers {
+				key, err := crypto.GenerateKey()
+				if err != nil {
+					h.fail("fund", err)
+					continue
+				}
+				// the funder sends one transfer at a time so its nonces stay
+				// in order
+				fundMu.Lock()
+				err = h.fund(ctx, funding, funderNonce, key)
+				if err == nil {
+					funderNonce++
+				} else if nonce, nerr := funding.client.PendingNonceAt(ctx, crypto.PubkeyToAddress(h.funder.PublicKey)); nerr == nil {
+					funderNonce = nonce
+				}
+				fundMu.Unlock()
+				if err != nil {
+					h.fail("fund", err)
+					continue
+				}
+				h.transfer(ctx, key)
+			}
+		}()
+	}
+	for i := 0; i < h.cfg.Transfers && ctx.Err() == nil; i++ {
+		transfers <- i
+	}
+	close(transfers)
+	wg.Wait()
+	h.report.End = time.Now()
+	h.report.finish()
+	return h.report, nil
+}
+
+// fund sends the account of a transfer what its legs spend.
+func (h *Harness) fund(ctx context.Context, c *chain, nonce uint64, key *ecdsa.PrivateKey) error {
+	to := crypto.PubkeyToAddress(key.PublicKey)
+	// the amount and fee of the first leg, plus gas for both legs
+	value := new(big.Int).Add(h.cfg.Amount, h.cfg.RelayFee)
+	value.Add(value, new(big.Int).Mul(c.gasPrice, big.NewInt(2*legGas)))
+	tx, err := c.sendFrom(ctx, h.funder, nonce, &to, value, nil, 21000)
+	if err != nil {
+		return err
+	}
+	_, err = util.WaitReceipt(ctx, c.client, tx.Hash())
+	return err
+}
+
+// legGas is the gas a deposit or withdrawal is given, its estimate is used
+// when it is higher.
+const legGas = 300000
+
+func (h *Harness) transfer(ctx context.Context, key *ecdsa.PrivateKey) {
+	switch h.cfg.Mode {
+	case ModeDeposit:
+		h.leg(ctx, ModeDeposit, key, h.cfg.Amount)
+	case ModeWithdraw:
+		h.leg(ctx, ModeWithdraw, key, h.cfg.Amount)
+	case ModeRoundTrip:
+		if !h.leg(ctx, ModeDeposit, key, h.cfg.Amount) {
+			return
+		}
+		// withdraw what arrived minus the fee and gas of the withdrawal
+		amount := new(big.Int).Sub(h.cfg.Amount, h.cfg.RelayFee)
+		amount.Sub(amount, new(big.Int).Mul(h.l2.gasPrice, big.NewInt(legGas)))
+		if amount.Sign() <= 0 {
+			h.fail(ModeWithdraw, fmt.Errorf("amount %s does not cover the relay fee and gas of the withdrawal", h.cfg.Amount))
+			return
+		}
+		h.leg(ctx, ModeWithdraw, key, amount)
+	}
+}
+
+// leg sends amount through one escrow and waits for the finalize event of
+// the other. It reports whether the message was delivered.
+func (h *Harness) leg(ctx context.Context, kind string, key *ecdsa.PrivateKey, amount *big.Int) bool {
+	src, dst := h.l1, h.l2
+	escrow, remoteEscrow := h.cfg.L1Escrow, h.cfg.L2Escrow
+	method, event := depositMethod, depositEvent
+	if kind == ModeWithdraw {
+		src, dst = h.l2, h.l1
+		escrow, remoteEscrow = h.cfg.L2Escrow, h.cfg.L1Escrow
+		method, event = withdrawMethod, withdrawalEvent
+	}
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		h.fail(kind, err)
+		return false
+	}
+	data, err := escrowAbi.Pack(method, account, amount, h.cfg.RelayFee, id)
+	if err != nil {
+		h.fail(kind, err)
+		return false
+	}
+	from, err := dst.client.BlockNumber(ctx)
+	if err != nil {
+		h.fail(kind, err)
+		return false
+	}
+	nonce, err := src.client.PendingNonceAt(ctx, account)
+	if err != nil {
+		h.fail(kind, err)
+		return false
+	}
+
+	submitted := time.Now()
+	value := new(big.Int).Add(amount, h.cfg.RelayFee)
+	tx, err := src.sendFrom(ctx, key, nonce, &escrow, value, data, legGas)
+	if err != nil {
+		h.fail(kind, err)
+		return false
+	}
+	_, err = util.WaitReceipt(ctx, src.client, tx.Hash())
+	if err != nil {
+		err = fmt.Errorf("%s %w", kind, err)
+		h.fail(kind, err)
+		return false
+	}
+	included := time.Now()
+
+	waitCtx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+	err = dst.waitEvent(waitCtx, remoteEscrow, event, account, id, from)
+	if err != nil {
+		if waitCtx.Err() != nil && ctx.Err() == nil {
+			err = fmt.Errorf("not delivered within %s", h.cfg.Timeout)
+		}
+		h.fail(kind, err)
+		return false
+	}
+	delivered := time.Now()
+	log15.Info("bridge message delivered", "kind", kind, "account", account, "relay", delivered.Sub(included).Round(time.Millisecond))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	leg := h.report.leg(kind)
+	leg.Delivered++
+	leg.inclusion = append(leg.inclusion, included.Sub(submitted))
+	leg.relay = append(leg.relay, delivered.Sub(included))
+	leg.finalization = append(leg.finalization, delivered.Sub(submitted))
+	return true
+}
+
+func (h *Harness) fail(kind string, err error) {
+	log15.Warn("bridge transfer failed", "kind", kind, "err", err)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	leg := h.report.leg(kind)
+	leg.Failed++
+	if leg.Errors == nil {
+		leg.Errors = map[string]int{}
+	}
+	leg.Errors[err.Error()]++
+}
+
+type chain struct {
+	name     string
+	client   *ethclient.Client
+	signer   types.Signer
+	gasPrice *big.Int
+}
+
+func dialChain(ctx context.Context, name, url string) (*chain, error) {
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to get the chain id of %s at %s, %w", name, url, err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &chain{
+		name:     name,
+		client:   client,
+		signer:   types.LatestSignerForChainID(chainId),
+		gasPrice: gasPrice,
+	}, nil
+}
+
+func (c *chain) sendFrom(
+	ctx context.Context,
+	key *ecdsa.PrivateKey,
+	nonce uint64,
+	to *common.Address,
+	value *big.Int,
+	data []byte,
+	gas uint64,
+) (*types.Transaction, error) {
+	if len(data) > 0 {
+		estimate, err := c.client.EstimateGas(ctx, ethereum.CallMsg{
+			From:  crypto.PubkeyToAddress(key.PublicKey),
+			To:    to,
+			Value: value,
+			Data:  data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s rejects the transaction, %w", c.name, err)
+		}
+		if estimate > gas {
+			gas = estimate
+		}
+	}
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: c.gasPrice,
+		Gas:      gas,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	}), c.signer, key)
+	if err != nil {
+		return nil, err
+	}
+	return tx, c.client.SendTransaction(ctx, tx)
+}
+
+// waitEvent waits for event of escrow to account carrying id as its data,
+// looking from block from on.
+func (c *chain) waitEvent(
+	ctx context.Context,
+	escrow common.Address,
+	event string,
+	account common.Address,
+	id []byte,
+	from uint64,
+) error {
+	ev := escrowAbi.Events[event]
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		Addresses: []common.Address{escrow},
+		Topics:    [][]common.Hash{{ev.ID}, nil, {common.BytesToHash(account.Bytes())}},
+	}
+	for {
+		logs, err := c.client.FilterLogs(ctx, query)
+		if err == nil {
+			for _, l := range logs {
+				values, err := ev.Inputs.NonIndexed().Unpack(l.Data)
+				if err != nil || len(values) != 2 {
+					continue
+				}
+				if data, ok := values[1].([]byte); ok && bytes.Equal(data, id) {
+					return nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// LegReport measures the deposits or withdrawals of a run. Inclusion is
+// from submission to the receipt on the source chain, relay from there to
+// the finalize event on the destination chain and finalization the whole.
+type LegReport struct {
+	Delivered    int            `json:"delivered"`
+	Failed       int            `json:"failed"`
+	Inclusion    Percentiles    `json:"inclusion"`
+	Relay        Percentiles    `json:"relay"`
+	Finalization Percentiles    `json:"finalization"`
+	Errors       map[string]int `json:"errors,omitempty"`
+	inclusion    []time.Duration
+	relay        []time.Duration
+	finalization []time.Duration
+}
+
+type Percentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	Max time.Duration `json:"max"`
+}
+
+type Report struct {
+	Mode        string                `json:"mode"`
+	Transfers   int                   `json:"transfers"`
+	Concurrency int                   `json:"concurrency"`
+	Start       time.Time             `json:"start"`
+	End         time.Time             `json:"end"`
+	Legs        map[string]*LegReport `json:"legs"`
+}
+
+func (r *Report) leg(kind string) *LegReport {
+	if r.Legs == nil {
+		r.Legs = map[string]*LegReport{}
+	}
+	if r.Legs[kind] == nil {
+		r.Legs[kind] = &LegReport{}
+	}
+	return r.Legs[kind]
+}
+
+func (r *Report) finish() {
+	for _, leg := range r.Legs {
+		leg.Inclusion = percentiles(leg.inclusion)
+		leg.Relay = percentiles(leg.relay)
+		leg.Finalization = percentiles(leg.finalization)
+	}
+}
+
+func percentiles(d []time.Duration) Percentiles {
+	if len(d) == 0 {
+		return Percentiles{}
+	}
+	stats.SortDurations(d)
+	return Percentiles{P50: stats.Percentile(d, 50), P90: stats.Percentile(d, 90), Max: d[len(d)-1]}
+}
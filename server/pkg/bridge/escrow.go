@@ -0,0 +1,41 @@
+package bridge
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// the parts of the L1Escrow and L2Escrow abis the harness uses, the full
+// abis are generated for the bridge of the website
+const escrowAbiJson = `[
+  {"type":"function","name":"depositETHTo","stateMutability":"payable","outputs":[],"inputs":[
+    {"name":"_to","type":"address"},{"name":"_amount","type":"uint256"},
+    {"name":"_relayFee","type":"uint256"},{"name":"_data","type":"bytes"}]},
+  {"type":"function","name":"withdrawETHTo","stateMutability":"payable","outputs":[],"inputs":[
+    {"name":"_to","type":"address"},{"name":"_amount","type":"uint256"},
+    {"name":"_relayFee","type":"uint256"},{"name":"_data","type":"bytes"}]},
+  {"type":"event","name":"ETHDepositFinalized","anonymous":false,"inputs":[
+    {"indexed":true,"name":"_from","type":"address"},{"indexed":true,"name":"_to","type":"address"},
+    {"indexed":false,"name":"_amount","type":"uint256"},{"indexed":false,"name":"_data","type":"bytes"}]},
+  {"type":"event","name":"ETHWithdrawalFinalized","anonymous":false,"inputs":[
+    {"indexed":true,"name":"_from","type":"address"},{"indexed":true,"name":"_to","type":"address"},
+    {"indexed":false,"name":"_amount","type":"uint256"},{"indexed":false,"name":"_data","type":"bytes"}]}
+]`
+
+const (
+	depositMethod   = "depositETHTo"
+	withdrawMethod  = "withdrawETHTo"
+	depositEvent    = "ETHDepositFinalized"
+	withdrawalEvent = "ETHWithdrawalFinalized"
+)
+
+var escrowAbi = mustParseAbi(escrowAbiJson)
+
+func mustParseAbi(s string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -66,9 +66,18 @@ func (f *LocalFileDatabase) DeleteRollup(name string) error {
 	return os.Remove(path)
 }
 
-func (f *LocalFileDatabase) GetRollupContracts(l1ChainId, l2ChainId int) (*types.RollupContracts, error) {
+func (f *LocalFileDatabase) rollupContractsPath() string {
+	return deploymentsPath("rollup_contracts.json")
+}
+
+// deploymentsPath is a file of the deployment records of the contracts repo.
+func deploymentsPath(file string) string {
 	curDir, _ := os.Getwd()
-	rollupContractsFile := path.Join(curDir, "../contracts/packages/protocol/deployments/rollup_contracts.json")
+	return path.Join(curDir, "../contracts/packages/protocol/deployments", file)
+}
+
+func (f *LocalFileDatabase) GetRollupContracts(l1ChainId, l2ChainId int) (*types.RollupContracts, error) {
+	rollupContractsFile := f.rollupContractsPath()
 	var rollupContracts []types.RollupContracts
 	err := util.ReadJSONTo(&rollupContracts, rollupContractsFile)
 	if err != nil {
@@ -82,6 +91,66 @@ func (f *LocalFileDatabase) GetRollupContracts(l1ChainId, l2ChainId int) (*types
 	return nil, fmt.Errorf("rollup contracts with l1ChainId:%d l2ChainId:%d not found", l1ChainId, l2ChainId)
 }
 
+// AddRollupContracts records contracts deployed from another host, e.g. of
+// a restored rollup. Contracts already recorded are left untouched.
+func (f *LocalFileDatabase) AddRollupContracts(contracts *types.RollupContracts) error {
+	if _, err := f.GetRollupContracts(contracts.L1ChainId, contracts.L2ChainId); err == nil {
+		return nil
+	}
+	rollupContractsFile := f.rollupContractsPath()
+	var rollupContracts []types.RollupContracts
+	err := util.ReadJSONTo(&rollupContracts, rollupContractsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(rollupContractsFile), 0755); err != nil {
+		return err
+	}
+	rollupContracts = append(rollupContracts, *contracts)
+	return util.WriteJSONTo(rollupContracts, rollupContractsFile)
+}
+
+func (f *LocalFileDatabase) GetL1VersionedLogics(l1ChainId, version int) (*types.L1VersionedLogics, error) {
+	var logics []types.L1VersionedLogics
+	if err := util.ReadJSONTo(&logics, deploymentsPath("l1_versioned_logics.json")); err != nil {
+		return nil, err
+	}
+	for _, item := range logics {
+		if item.L1ChainId == l1ChainId && item.Version == version {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("l1 logics with l1ChainId:%d version:%d not found", l1ChainId, version)
+}
+
+func (f *LocalFileDatabase) GetL2VersionedLogics(l1ChainId, l2ChainId, version int) (*types.L2VersionedLogics, error) {
+	var logics []types.L2VersionedLogics
+	if err := util.ReadJSONTo(&logics, deploymentsPath("l2_versioned_logics.json")); err != nil {
+		return nil, err
+	}
+	for _, item := range logics {
+		if item.L1ChainId == l1ChainId && item.L2ChainId == l2ChainId && item.Version == version {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("l2 logics with l1ChainId:%d l2ChainId:%d version:%d not found", l1ChainId, l2ChainId, version)
+}
+
+// GetL1Logic returns the l1 contract name deployed without a version, such
+// as the proxy admin shared by the rollups of an l1.
+func (f *LocalFileDatabase) GetL1Logic(l1ChainId int, name string) (*types.ContractLogic, error) {
+	var logics []types.ContractLogic
+	if err := util.ReadJSONTo(&logics, deploymentsPath("l1_logics.json")); err != nil {
+		return nil, err
+	}
+	for _, item := range logics {
+		if item.L1ChainId == l1ChainId && item.ContractName == name {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("l1 logic %s with l1ChainId:%d not found", name, l1ChainId)
+}
+
 func (f *LocalFileDatabase) GetRollups() ([]*types.Rollup, error) {
 	files, err := ioutil.ReadDir(f.dbRootDir)
 	if err != nil {
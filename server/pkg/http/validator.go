@@ -11,10 +11,17 @@ type CustomValidator struct {
 	Validator *validator.Validate
 }
 
+// Validate checks the validate tags of i, then its own Validate method if it
+// has one.
 func (cv *CustomValidator) Validate(i interface{}) error {
 	if err := cv.Validator.Struct(i); err != nil {
 		// Optionally, you could return the error to give each route more control over the status code
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
+	if v, ok := i.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
 	return nil
 }
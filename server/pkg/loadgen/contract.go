@@ -0,0 +1,182 @@
+package loadgen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	transferSelector = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+	fillSelector     = crypto.Keccak256([]byte("fill(uint256)"))[:4]
+	transferTopic    = crypto.Keccak256([]byte("Transfer(address,address,uint256)"))
+
+	// tokenSupply is minted to the deployer of the token.
+	tokenSupply = new(big.Int).Lsh(big.NewInt(1), 128)
+
+	// the counter of fill and the base of the slots it writes, both above
+	// the address range of the balance slots
+	fillCounterSlot = bytes.Repeat([]byte{0xff}, 32)
+	fillBaseSlot    = new(big.Int).Lsh(big.NewInt(1), 255).Bytes()
+)
+
+// tokenCode is the init code of the contract the load runs against. It is
+// an ERC20 subset, transfer(address,uint256) moving balances kept in the
+// slot of each address and emitting Transfer, with fill(uint256) writing
+// that many fresh storage slots.
+func tokenCode() []byte {
+	rt := &assembler{}
+	rt.push([]byte{0})
+	rt.op(vm.CALLDATALOAD)
+	rt.push([]byte{0xe0})
+	rt.op(vm.SHR)
+	rt.op(vm.DUP1)
+	rt.push(transferSelector)
+	rt.op(vm.EQ)
+	rt.jumpi("transfer")
+	rt.op(vm.DUP1)
+	rt.push(fillSelector)
+	rt.op(vm.EQ)
+	rt.jumpi("fill")
+	rt.label("fail")
+	rt.push([]byte{0})
+	rt.op(vm.DUP1, vm.REVERT)
+
+	// stack: amount, balance of the caller
+	rt.label("transfer")
+	rt.op(vm.CALLER, vm.SLOAD)
+	rt.push([]byte{0x24})
+	rt.op(vm.CALLDATALOAD, vm.DUP1, vm.DUP3, vm.LT)
+	rt.jumpi("fail")
+	rt.op(vm.DUP1, vm.DUP3, vm.SUB, vm.CALLER, vm.SSTORE)
+	rt.push([]byte{0x04})
+	rt.op(vm.CALLDATALOAD, vm.DUP1, vm.SLOAD, vm.DUP3, vm.ADD, vm.SWAP1, vm.SSTORE)
+	rt.push([]byte{0})
+	rt.op(vm.MSTORE)
+	rt.push([]byte{0x04})
+	rt.op(vm.CALLDATALOAD, vm.CALLER)
+	rt.push(transferTopic)
+	rt.push([]byte{0x20})
+	rt.push([]byte{0})
+	rt.op(vm.LOG3)
+	rt.push([]byte{1})
+	rt.push([]byte{0})
+	rt.op(vm.MSTORE)
+	rt.push([]byte{0x20})
+	rt.push([]byte{0})
+	rt.op(vm.RETURN)
+
+	// stack: remaining slots
+	rt.label("fill")
+	rt.push([]byte{0x04})
+	rt.op(vm.CALLDATALOAD)
+	rt.label("loop")
+	rt.op(vm.DUP1, vm.ISZERO)
+	rt.jumpi("done")
+	rt.push(fillCounterSlot)
+	rt.op(vm.SLOAD)
+	rt.push([]byte{1})
+	rt.op(vm.ADD, vm.DUP1)
+	rt.push(fillCounterSlot)
+	rt.op(vm.SSTORE, vm.DUP1, vm.DUP1)
+	rt.push(fillBaseSlot)
+	rt.op(vm.OR, vm.SSTORE, vm.POP)
+	rt.push([]byte{1})
+	rt.op(vm.SWAP1, vm.SUB)
+	rt.jump("loop")
+	rt.label("done")
+	rt.op(vm.STOP)
+	runtime := rt.bytes()
+
+	// the init code copies the runtime from its own end, so it is assembled
+	// once to learn its length
+	init := tokenInitCode(runtime, 0)
+	return append(tokenInitCode(runtime, len(init)), runtime...)
+}
+
+func tokenInitCode(runtime []byte, offset int) []byte {
+	init := &assembler{}
+	init.push(tokenSupply.Bytes())
+	init.op(vm.CALLER, vm.SSTORE)
+	init.push(uint16Bytes(len(runtime)))
+	init.op(vm.DUP1)
+	init.push(uint16Bytes(offset))
+	init.push([]byte{0})
+	init.op(vm.CODECOPY)
+	init.push([]byte{0})
+	init.op(vm.RETURN)
+	return init.bytes()
+}
+
+func transferData(to common.Address, amount *big.Int) []byte {
+	data := append([]byte{}, transferSelector...)
+	data = append(data, common.LeftPadBytes(to.Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+}
+
+func fillData(slots int) []byte {
+	data := append([]byte{}, fillSelector...)
+	return append(data, common.LeftPadBytes(big.NewInt(int64(slots)).Bytes(), 32)...)
+}
+
+func uint16Bytes(n int) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(n))
+	return b
+}
+
+// assembler writes evm code, resolving jumps to labels.
+type assembler struct {
+	code   []byte
+	labels map[string]int
+	jumps  map[int]string
+}
+
+func (a *assembler) op(ops ...vm.OpCode) {
+	for _, op := range ops {
+		a.code = append(a.code, byte(op))
+	}
+}
+
+func (a *assembler) push(data []byte) {
+	a.code = append(a.code, byte(vm.PUSH1)+byte(len(data)-1))
+	a.code = append(a.code, data...)
+}
+
+func (a *assembler) label(name string) {
+	if a.labels == nil {
+		a.labels = map[string]int{}
+	}
+	a.labels[name] = len(a.code)
+	a.op(vm.JUMPDEST)
+}
+
+func (a *assembler) jump(label string) {
+	a.pushLabel(label)
+	a.op(vm.JUMP)
+}
+
+func (a *assembler) jumpi(label string) {
+	a.pushLabel(label)
+	a.op(vm.JUMPI)
+}
+
+func (a *assembler) pushLabel(label string) {
+	if a.jumps == nil {
+		a.jumps = map[int]string{}
+	}
+	a.op(vm.PUSH2)
+	a.jumps[len(a.code)] = label
+	a.code = append(a.code, 0, 0)
+}
+
+func (a *assembler) bytes() []byte {
+	for at, label := range a.jumps {
+		binary.BigEndian.PutUint16(a.code[at:], uint16(a.labels[label]))
+	}
+	return a.code
+}
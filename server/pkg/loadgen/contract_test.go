@@ -0,0 +1,81 @@
+package loadgen
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+func balanceOf(statedb *state.StateDB, token, owner common.Address) *big.Int {
+	return statedb.GetState(token, common.BytesToHash(owner.Bytes())).Big()
+}
+
+func TestTokenTransfersAndFills(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployer := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	other := common.HexToAddress("0x2000000000000000000000000000000000000002")
+	cfg := &runtime.Config{Origin: deployer, State: statedb, GasLimit: 10000000}
+
+	_, token, _, err := runtime.Create(tokenCode(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := balanceOf(statedb, token, deployer); got.Cmp(tokenSupply) != 0 {
+		t.Fatalf("deployer holds %s, want the supply %s", got, tokenSupply)
+	}
+
+	ret, _, err := runtime.Call(token, transferData(other, big.NewInt(100)), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(ret).Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("transfer returned %x, want true", ret)
+	}
+	if got := balanceOf(statedb, token, other); got.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("recipient holds %s, want 100", got)
+	}
+	if got, want := balanceOf(statedb, token, deployer), new(big.Int).Sub(tokenSupply, big.NewInt(100)); got.Cmp(want) != 0 {
+		t.Fatalf("deployer holds %s, want %s", got, want)
+	}
+	logs := statedb.Logs()
+	if len(logs) != 1 {
+		t.Fatalf("transfer emitted %d logs, want 1", len(logs))
+	}
+	topics := logs[0].Topics
+	if len(topics) != 3 || topics[0] != common.BytesToHash(transferTopic) ||
+		topics[1] != common.BytesToHash(deployer.Bytes()) || topics[2] != common.BytesToHash(other.Bytes()) {
+		t.Fatalf("transfer emitted topics %v", topics)
+	}
+	if got := new(big.Int).SetBytes(logs[0].Data); got.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("transfer logged amount %s, want 100", got)
+	}
+
+	// the recipient can not send more than it holds
+	cfg.Origin = other
+	if _, _, err := runtime.Call(token, transferData(deployer, big.NewInt(101)), cfg); err == nil {
+		t.Fatal("transfer above the balance did not revert")
+	}
+	if got := balanceOf(statedb, token, other); got.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("recipient holds %s after the reverted transfer, want 100", got)
+	}
+
+	if _, _, err := runtime.Call(token, fillData(3), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := statedb.GetState(token, common.BytesToHash(fillCounterSlot)).Big(); got.Cmp(big.NewInt(3)) != 0 {
+		t.Fatalf("fill counter is %s, want 3", got)
+	}
+	for i := int64(1); i <= 3; i++ {
+		slot := new(big.Int).Or(new(big.Int).SetBytes(fillBaseSlot), big.NewInt(i))
+		if got := statedb.GetState(token, common.BigToHash(slot)).Big(); got.Cmp(big.NewInt(i)) != 0 {
+			t.Fatalf("fill slot %d holds %s", i, got)
+		}
+	}
+}
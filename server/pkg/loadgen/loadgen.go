@@ -0,0 +1,521 @@
+// Package loadgen sends a configurable mix of transactions to an l2 node at
+// a target rate and reports how many were included and how fast.
+package loadgen
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/g1g2-lab/automation/pkg/stats"
+	"github.com/g1g2-lab/automation/util"
+	"github.com/inconshreveable/log15"
+)
+
+// transaction kinds of a mix
+const (
+	KindTransfer = "transfer"
+	KindDeploy   = "deploy"
+	KindErc20    = "erc20"
+	KindStorage  = "storage"
+)
+
+var kinds = []string{KindTransfer, KindDeploy, KindErc20, KindStorage}
+
+// DefaultMix weighs the transaction kinds when none is given.
+var DefaultMix = map[string]int{
+	KindTransfer: 70,
+	KindErc20:    20,
+	KindDeploy:   5,
+	KindStorage:  5,
+}
+
+type Config struct {
+	RpcUrl string
+	// FunderKey is the hex private key funding the generated accounts.
+	FunderKey   string
+	Accounts    int
+	Tps         float64
+	Concurrency int
+	Duration    time.Duration
+	// Drain is how long to wait for submitted transactions after Duration.
+	Drain time.Duration
+	// Fund is the amount in wei sent to each generated account.
+	Fund *big.Int
+	Mix  map[string]int
+	// StorageSlots is the number of fresh slots a storage call writes.
+	StorageSlots int
+}
+
+// ParseMix parses weights like "transfer=70,erc20=30".
+func ParseMix(s string) (map[string]int, error) {
+	mix := map[string]int{}
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		kind, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("mix entry must be <kind>=<weight>, got %q", part)
+		}
+		if !validKind(kind) {
+			return nil, fmt.Errorf("unknown transaction kind %s, expected one of %s", kind, strings.Join(kinds, ", "))
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non-negative integer, got %q", kind, weight)
+		}
+		mix[kind] = w
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix %q has no positive weight", s)
+	}
+	return mix, nil
+}
+
+func validKind(kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) Validate() error {
+	if c.RpcUrl == "" {
+		return fmt.Errorf("rpc url is required")
+	}
+	if c.FunderKey == "" {
+		return fmt.Errorf("funder key is required")
+	}
+	if c.Accounts <= 0 || c.Concurrency <= 0 || c.Tps <= 0 || c.Duration <= 0 {
+		return fmt.Errorf("accounts, concurrency, tps and duration must be positive")
+	}
+	if c.StorageSlots <= 0 {
+		return fmt.Errorf("storage slots must be positive")
+	}
+	return nil
+}
+
+type account struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+	nonce   uint64
+}
+
+func newAccount(key *ecdsa.PrivateKey) *account {
+	return &account{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+type sent struct {
+	kind string
+	at   time.Time
+}
+
+// Generator runs a load. Accounts are split between the workers so each
+// worker owns the nonces of its accounts.
+type Generator struct {
+	cfg      Config
+	client   *ethclient.Client
+	signer   types.Signer
+	gasPrice *big.Int
+	funder   *account
+	accounts []*account
+	token    common.Address
+	mix      []string
+
+	mu      sync.Mutex
+	pending map[common.Hash]sent
+	report  *Report
+}
+
+func New(ctx context.Context, cfg Config) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Mix == nil {
+		cfg.Mix = DefaultMix
+	}
+	if cfg.Concurrency > cfg.Accounts {
+		cfg.Concurrency = cfg.Accounts
+	}
+	client, err := ethclient.DialContext(ctx, cfg.RpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the chain id of %s, %w", cfg.RpcUrl, err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(cfg.FunderKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid funder key, %w", err)
+	}
+	g := &Generator{
+		cfg:      cfg,
+		client:   client,
+		signer:   types.LatestSignerForChainID(chainId),
+		gasPrice: gasPrice,
+		funder:   newAccount(key),
+		pending:  map[common.Hash]sent{},
+		report:   &Report{RpcUrl: cfg.RpcUrl, TargetTps: cfg.Tps, Kinds: map[string]*KindReport{}},
+	}
+	for kind, weight := range cfg.Mix {
+		for i := 0; i < weight; i++ {
+			g.mix = append(g.mix, kind)
+		}
+		g.report.Kinds[kind] = &KindReport{}
+	}
+	return g, nil
+}
+
+// Run funds the accounts, deploys the token when the mix needs it, sends
+// the load until Duration passes or ctx is done and waits Drain for the
+// submitted transactions.
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	defer g.client.Close()
+	if err := g.setup(ctx); err != nil {
+		return nil, err
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, g.cfg.Duration)
+	defer cancel()
+	trackCtx, stopTracking := context.WithCancel(context.Background())
+	defer stopTracking()
+	head, err := g.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(chan struct{})
+	go func() {
+		defer close(tracked)
+		g.track(trackCtx, head)
+	}()
+
+	log15.Info("sending load", "rpc", g.cfg.RpcUrl, "tps", g.cfg.Tps, "accounts", len(g.accounts), "concurrency", g.cfg.Concurrency, "duration", g.cfg.Duration)
+	g.report.Start = time.Now()
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < g.cfg.Concurrency; w++ {
+		owned := []*account{}
+		for i := w; i < len(g.accounts); i += g.cfg.Concurrency {
+			owned = append(owned, g.accounts[i])
+		}
+		wg.Add(1)
+		go func(owned []*account) {
+			defer wg.Done()
+			g.work(loadCtx, owned, jobs)
+		}(owned)
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / g.cfg.Tps))
+	defer ticker.Stop()
+produce:
+	for {
+		select {
+		case <-loadCtx.Done():
+			break produce
+		case <-ticker.C:
+			select {
+			case jobs <- struct{}{}:
+			default:
+				// every worker is busy, the node is slower than the target
+				g.mu.Lock()
+				g.report.Skipped++
+				g.mu.Unlock()
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	g.report.End = time.Now()
+
+	deadline := time.Now().Add(g.cfg.Drain)
+	for time.Now().Before(deadline) && g.pendingCount() > 0 && ctx.Err() == nil {
+		time.Sleep(500 * time.Millisecond)
+	}
+	stopTracking()
+	<-tracked
+	g.report.finish(g.pendingCount())
+	return g.report, nil
+}
+
+func (g *Generator) pendingCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.pending)
+}
+
+func (g *Generator) work(ctx context.Context, owned []*account, jobs <-chan struct{}) {
+	next := 0
+	for range jobs {
+		if ctx.Err() != nil {
+			continue
+		}
+		from := owned[next%len(owned)]
+		next++
+		kind := g.mix[rand.Intn(len(g.mix))]
+		tx, err := g.sign(from, kind)
+		g.mu.Lock()
+		g.report.Kinds[kind].Submitted++
+		g.report.Submitted++
+		if err == nil {
+			// tracked before it is sent, the block including it may be
+			// settled before SendTransaction returns
+			g.pending[tx.Hash()] = sent{kind: kind, at: time.Now()}
+		}
+		g.mu.Unlock()
+		if err == nil {
+			err = g.client.SendTransaction(ctx, tx)
+		}
+		if err == nil {
+			from.nonce++
+			continue
+		}
+		g.mu.Lock()
+		settled := false
+		if tx != nil {
+			_, pending := g.pending[tx.Hash()]
+			settled = !pending
+			delete(g.pending, tx.Hash())
+		}
+		if !settled {
+			g.report.Kinds[kind].Failed++
+			g.report.Failed++
+			g.report.addError(err)
+		}
+		g.mu.Unlock()
+		// the nonce may or may not have been used, ask the node
+		if nonce, err := g.client.PendingNonceAt(ctx, from.address); err == nil {
+			from.nonce = nonce
+		}
+	}
+}
+
+// sign signs the next transaction of kind from the account.
+func (g *Generator) sign(from *account, kind string) (*types.Transaction, error) {
+	var to *common.Address
+	var value *big.Int
+	var data []byte
+	var gas uint64
+	switch kind {
+	case KindTransfer:
+		other := g.accounts[rand.Intn(len(g.accounts))].address
+		to, value, gas = &other, big.NewInt(1), 21000
+	case KindDeploy:
+		data, gas = tokenCode(), 500000
+	case KindErc20:
+		other := g.accounts[rand.Intn(len(g.accounts))].address
+		to, data, gas = &g.token, transferData(other, big.NewInt(1)), 100000
+	case KindStorage:
+		to, data, gas = &g.token, fillData(g.cfg.StorageSlots), uint64(50000+35000*g.cfg.StorageSlots)
+	}
+	return g.signTx(from, to, value, data, gas)
+}
+
+func (g *Generator) signTx(
+	from *account,
+	to *common.Address,
+	value *big.Int,
+	data []byte,
+	gas uint64,
+) (*types.Transaction, error) {
+	if value == nil {
+		value = new(big.Int)
+	}
+	return types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    from.nonce,
+		GasPrice: g.gasPrice,
+		Gas:      gas,
+		To:       to,
+		Value:    value,
+		Data:     data,
+	}), g.signer, from.key)
+}
+
+func (g *Generator) sendTx(
+	ctx context.Context,
+	from *account,
+	to *common.Address,
+	value *big.Int,
+	data []byte,
+	gas uint64,
+) (*types.Transaction, error) {
+	tx, err := g.signTx(from, to, value, data, gas)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	from.nonce++
+	return tx, nil
+}
+
+// setup funds fresh accounts from the funder, and deploys the token and
+// hands each account a share when the mix calls it.
+func (g *Generator) setup(ctx context.Context) error {
+	nonce, err := g.client.PendingNonceAt(ctx, g.funder.address)
+	if err != nil {
+		return err
+	}
+	g.funder.nonce = nonce
+	txs := []*types.Transaction{}
+	for i := 0; i < g.cfg.Accounts; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		a := newAccount(key)
+		g.accounts = append(g.accounts, a)
+		tx, err := g.sendTx(ctx, g.funder, &a.address, g.cfg.Fund, nil, 21000)
+		if err != nil {
+			return fmt.Errorf("failed to fund account %s, %w", a.address, err)
+		}
+		txs = append(txs, tx)
+	}
+	if g.cfg.Mix[KindErc20] > 0 || g.cfg.Mix[KindStorage] > 0 {
+		tx, err := g.sendTx(ctx, g.funder, nil, nil, tokenCode(), 500000)
+		if err != nil {
+			return fmt.Errorf("failed to deploy the token, %w", err)
+		}
+		g.token = crypto.CreateAddress(g.funder.address, tx.Nonce())
+		txs = append(txs, tx)
+		share := new(big.Int).Div(tokenSupply, big.NewInt(int64(2*g.cfg.Accounts)))
+		for _, a := range g.accounts {
+			tx, err := g.sendTx(ctx, g.funder, &g.token, nil, transferData(a.address, share), 100000)
+			if err != nil {
+				return fmt.Errorf("failed to send tokens to %s, %w", a.address, err)
+			}
+			txs = append(txs, tx)
+		}
+	}
+	log15.Info("waiting for setup transactions", "count", len(txs), "funder", g.funder.address)
+	for _, tx := range txs {
+		if _, err := util.WaitReceipt(ctx, g.client, tx.Hash()); err != nil {
+			return fmt.Errorf("setup transaction failed, %w", err)
+		}
+	}
+	return nil
+}
+
+// track follows new blocks from head and settles the pending transactions
+// they include.
+func (g *Generator) track(ctx context.Context, head uint64) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	next := head + 1
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		latest, err := g.client.BlockNumber(ctx)
+		if err != nil {
+			continue
+		}
+		for ; next <= latest; next++ {
+			block, err := g.client.BlockByNumber(ctx, new(big.Int).SetUint64(next))
+			if err != nil {
+				break
+			}
+			g.settle(ctx, block)
+		}
+	}
+}
+
+func (g *Generator) settle(ctx context.Context, block *types.Block) {
+	seen := time.Now()
+	for _, tx := range block.Transactions() {
+		g.mu.Lock()
+		s, ok := g.pending[tx.Hash()]
+		g.mu.Unlock()
+		if !ok {
+			continue
+		}
+		status := types.ReceiptStatusSuccessful
+		if receipt, err := g.client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+			status = receipt.Status
+		}
+		g.mu.Lock()
+		if _, ok := g.pending[tx.Hash()]; !ok {
+			// counted as failed meanwhile
+			g.mu.Unlock()
+			continue
+		}
+		delete(g.pending, tx.Hash())
+		kind := g.report.Kinds[s.kind]
+		if status == types.ReceiptStatusSuccessful {
+			g.report.Included++
+			kind.Included++
+			g.report.latencies = append(g.report.latencies, seen.Sub(s.at))
+			g.report.LastInclusion = seen
+		} else {
+			g.report.Failed++
+			kind.Failed++
+			g.report.addError(fmt.Errorf("%s transaction reverted", s.kind))
+		}
+		g.mu.Unlock()
+	}
+}
+
+// KindReport counts the transactions of one kind.
+type KindReport struct {
+	Submitted int `json:"submitted"`
+	Included  int `json:"included"`
+	Failed    int `json:"failed"`
+}
+
+type Report struct {
+	RpcUrl    string                 `json:"rpc_url"`
+	Start     time.Time              `json:"start"`
+	End       time.Time              `json:"end"`
+	Submitted int                    `json:"submitted"`
+	Included  int                    `json:"included"`
+	Failed    int                    `json:"failed"`
+	Pending   int                    `json:"pending"`
+	Skipped   int                    `json:"skipped"`
+	Kinds     map[string]*KindReport `json:"kinds"`
+	// latencies from submission to the first poll seeing the block
+	LatencyP50    time.Duration  `json:"latency_p50"`
+	LatencyP90    time.Duration  `json:"latency_p90"`
+	LatencyP99    time.Duration  `json:"latency_p99"`
+	TargetTps     float64        `json:"target_tps"`
+	AchievedTps   float64        `json:"achieved_tps"`
+	LastInclusion time.Time      `json:"last_inclusion"`
+	Errors        map[string]int `json:"errors,omitempty"`
+
+	latencies []time.Duration
+}
+
+func (r *Report) addError(err error) {
+	if r.Errors == nil {
+		r.Errors = map[string]int{}
+	}
+	r.Errors[err.Error()]++
+}
+
+func (r *Report) finish(pending int) {
+	r.Pending = pending
+	stats.SortDurations(r.latencies)
+	r.LatencyP50 = stats.Percentile(r.latencies, 50)
+	r.LatencyP90 = stats.Percentile(r.latencies, 90)
+	r.LatencyP99 = stats.Percentile(r.latencies, 99)
+	if elapsed := r.LastInclusion.Sub(r.Start).Seconds(); r.Included > 0 && elapsed > 0 {
+		r.AchievedTps = float64(r.Included) / elapsed
+	}
+}
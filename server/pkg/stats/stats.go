@@ -0,0 +1,26 @@
+// Package stats summarizes the durations the load and bridge harnesses
+// measure.
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// SortDurations sorts d in place, shortest first.
+func SortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
+
+// Percentile of sorted durations, by the nearest rank. It is 0 for no
+// durations.
+func Percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
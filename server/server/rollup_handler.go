@@ -1,8 +1,11 @@
 package server
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/g1g2-lab/automation/api"
 	"github.com/g1g2-lab/automation/l2"
 
 	"github.com/g1g2-lab/automation/pkg/db"
@@ -36,11 +39,36 @@ func NewRollupHandler(
 }
 
 func (h *RollupHandler) SetupRollupRouter(e *echo.Echo, db *db.LocalFileDatabase) {
-	api := e.Group("/api/v1")
-	api.GET("/rollup/:id", h.getRollup)
-	api.POST("/rollup/:id", h.createRollup)
-	api.DELETE("/rollup/:id", h.deleteRollup)
-	api.GET("/rollups", h.getRollups)
+	g := e.Group(api.Prefix)
+	g.GET("/openapi.json", h.getOpenAPI)
+	g.GET("/rollup/:id", h.getRollup)
+	g.POST("/rollup/:id", h.createRollup)
+	g.DELETE("/rollup/:id", h.deleteRollup)
+	g.POST("/rollup/:id/stop", h.transitionRollup(types.Stopped))
+	g.POST("/rollup/:id/start", h.transitionRollup(types.Online))
+	g.POST("/rollup/:id/archive", h.transitionRollup(types.Archived))
+	g.POST("/rollup/:id/fork", h.forkRollup)
+	g.POST("/rollup/:id/backups", h.backupRollup)
+	g.GET("/rollup/:id/backups", h.getBackups)
+	g.POST("/rollups/restore", h.restoreRollup)
+	g.GET("/rollup/:id/status", h.getRollupStatus)
+	g.GET("/rollup/:id/logs", h.getRollupLogs)
+	g.GET("/rollup/:id/prover", h.getProverStatus)
+	g.POST("/rollup/:id/verify", h.verifyRollup)
+	g.POST("/rollup/:id/faucet", h.faucet)
+	g.POST("/rollup/:id/wallets", h.fundWallets)
+	g.GET("/rollup/:id/wallets", h.getWallets)
+	g.GET("/rollup/:id/chain.json", h.getChainMetadata)
+	g.GET("/rollup/:id/contracts", h.getContracts)
+	g.PUT("/rollup/:id/replicas", h.scaleReplicas)
+	g.POST("/rollup/:id/jwt/rotate", h.rotateJwtSecret)
+	g.POST("/rollup/:id/upgrade", h.upgradeContracts)
+	g.POST("/rollup/:id/images/upgrade", h.upgradeImage)
+	g.GET("/rollups", h.getRollups)
+}
+
+func (h *RollupHandler) getOpenAPI(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, api.Spec)
 }
 
 func (h *RollupHandler) getRollup(c echo.Context) error {
@@ -52,6 +80,30 @@ func (h *RollupHandler) getRollup(c echo.Context) error {
 	return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
 }
 
+func (h *RollupHandler) getRollupStatus(c echo.Context) error {
+	job, err := h.mgr.RollupStatus(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(job))
+}
+
+func (h *RollupHandler) getRollupLogs(c echo.Context) error {
+	tail := 100
+	if s := c.QueryParam("tail"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, types.ResponseWithError("tail must be a non-negative integer"))
+		}
+		tail = n
+	}
+	logs, err := h.mgr.RollupLogs(c.Param("id"), c.QueryParam("service"), tail)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(logs))
+}
+
 func (h *RollupHandler) getRollups(c echo.Context) error {
 	rollups, err := h.mgr.db.GetRollups()
 	if err != nil {
@@ -70,18 +122,204 @@ func (h *RollupHandler) createRollup(c echo.Context) error {
 	if err := c.Validate(&objRequest); err != nil {
 		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
 	}
-	err := h.mgr.CreateRollup(&objRequest)
+	if dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run")); dryRun {
+		plan, err := h.mgr.PlanRollup(&objRequest)
+		if err != nil {
+			return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+		}
+		return c.JSON(http.StatusOK, types.ResponseWithData(plan))
+	}
+	job, err := h.mgr.CreateRollup(&objRequest)
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusAccepted, types.ResponseWithData(job))
+}
+
+func (h *RollupHandler) forkRollup(c echo.Context) error {
+	var objRequest types.ForkRollupRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	if err := c.Validate(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	job, err := h.mgr.ForkRollup(c.Param("id"), &objRequest)
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusAccepted, types.ResponseWithData(job))
+}
+
+func (h *RollupHandler) getProverStatus(c echo.Context) error {
+	status, err := h.mgr.ProverStatus(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(status))
+}
+
+func (h *RollupHandler) verifyRollup(c echo.Context) error {
+	verification, err := h.mgr.VerifyRollup(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(verification))
+}
+
+func (h *RollupHandler) faucet(c echo.Context) error {
+	var objRequest types.FaucetRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	if err := c.Validate(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	drip, err := h.mgr.Faucet(c.Param("id"), objRequest.Address, c.RealIP())
+	if errors.Is(err, l2.ErrFaucetLimited) {
+		return c.JSON(http.StatusTooManyRequests, types.ResponseWithError(err.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(drip))
+}
+
+func (h *RollupHandler) fundWallets(c echo.Context) error {
+	var objRequest types.FundWalletsRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	if err := c.Validate(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	transfers, err := h.mgr.FundWallets(c.Param("id"), objRequest.Wallets)
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(transfers))
+}
+
+func (h *RollupHandler) getWallets(c echo.Context) error {
+	balances, err := h.mgr.WalletBalances(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(balances))
+}
+
+func (h *RollupHandler) getChainMetadata(c echo.Context) error {
+	metadata, err := h.mgr.ChainMetadata(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(metadata))
+}
+
+func (h *RollupHandler) getContracts(c echo.Context) error {
+	registry, err := h.mgr.Contracts(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(registry))
+}
+
+func (h *RollupHandler) scaleReplicas(c echo.Context) error {
+	var objRequest types.ScaleReplicasRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	rollup, err := h.mgr.ScaleReplicas(c.Param("id"), objRequest.Replicas)
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
+}
+
+func (h *RollupHandler) rotateJwtSecret(c echo.Context) error {
+	rollup, err := h.mgr.RotateJwtSecret(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
+}
+
+func (h *RollupHandler) upgradeContracts(c echo.Context) error {
+	var objRequest types.UpgradeContractsRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	if err := c.Validate(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	rollup, err := h.mgr.UpgradeContracts(c.Param("id"), objRequest.Version)
 	if err != nil {
 		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
 	}
-	return c.JSON(http.StatusCreated, types.ResponseWithData("Rollup is created"))
+	return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
+}
+
+func (h *RollupHandler) upgradeImage(c echo.Context) error {
+	var objRequest types.UpgradeImageRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	if err := c.Validate(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	rollup, err := h.mgr.UpgradeImage(c.Param("id"), objRequest.Image, objRequest.Version)
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
+}
+
+func (h *RollupHandler) transitionRollup(to int) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		rollup, err := h.mgr.TransitionRollup(c.Param("id"), to)
+		if err != nil {
+			return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+		}
+		return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
+	}
+}
+
+func (h *RollupHandler) backupRollup(c echo.Context) error {
+	backup, err := h.mgr.BackupRollup(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(backup))
+}
+
+func (h *RollupHandler) getBackups(c echo.Context) error {
+	backups, err := h.mgr.ListBackups(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(backups))
+}
+
+func (h *RollupHandler) restoreRollup(c echo.Context) error {
+	var objRequest types.RestoreRollupRequest
+	if err := c.Bind(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	if err := c.Validate(&objRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
+	}
+	rollup, err := h.mgr.RestoreRollup(objRequest.File, objRequest.Name)
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, types.ResponseWithError(err.Error()))
+	}
+	return c.JSON(http.StatusOK, types.ResponseWithData(rollup))
 }
 
 func (h *RollupHandler) deleteRollup(c echo.Context) error {
 	name := c.Param("id")
-	err := h.mgr.DeleteRollup(name)
+	err := h.mgr.DeleteRollup(name, c.QueryParam("confirm"))
 	if err != nil {
-		return err
+		return c.JSON(http.StatusBadRequest, types.ResponseWithError(err.Error()))
 	}
 	return c.JSON(http.StatusOK, types.ResponseWithMsg("Rollup deleted"))
 }
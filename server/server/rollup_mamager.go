@@ -2,49 +2,392 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
 
 	"github.com/g1g2-lab/automation/l2"
 	"github.com/g1g2-lab/automation/pkg/db"
 	"github.com/g1g2-lab/automation/types"
+	"github.com/inconshreveable/log15"
 )
 
 type Manager struct {
-	db   *db.LocalFileDatabase
-	cfg  *l2.L2Config
-	jobs map[string]chan bool
+	db      *db.LocalFileDatabase
+	cfg     *l2.L2Config
+	volumes l2.VolumeRuntime
+	faucets *l2.Faucets
+	mu      sync.Mutex
+	jobs    map[string]*types.RollupJob
+	// locks serialize the operations on a rollup, a provision holds the lock
+	// of its rollup until it is done.
+	locks map[string]*sync.Mutex
 }
 
 func NewRollupManager(db *db.LocalFileDatabase,
 	cfg *l2.L2Config) *Manager {
 	return &Manager{
-		db,
-		cfg,
-		map[string]chan bool{},
+		db:      db,
+		cfg:     cfg,
+		volumes: l2.NewDockerVolumes(),
+		faucets: l2.NewFaucets(cfg),
+		jobs:    map[string]*types.RollupJob{},
+		locks:   map[string]*sync.Mutex{},
 	}
 }
 
+// rollupLock returns the lock of rollup name.
+func (m *Manager) rollupLock(name string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.locks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[name] = lock
+	}
+	return lock
+}
+
+// provisioning returns the error of operations on a rollup that is being
+// provisioned.
+func (m *Manager) provisioning(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, ok := m.jobs[name]; ok && !job.Done() {
+		return fmt.Errorf("rollup %s is being provisioned", name)
+	}
+	return nil
+}
+
+// lockRollups takes the locks of the rollups in name order and fails when one
+// of them is being provisioned. The returned func releases the locks.
+func (m *Manager) lockRollups(names ...string) (func(), error) {
+	for _, name := range names {
+		// fail fast instead of waiting for a provision to finish
+		if err := m.provisioning(name); err != nil {
+			return nil, err
+		}
+	}
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	locks := []*sync.Mutex{}
+	unlock := func() {
+		for _, lock := range locks {
+			lock.Unlock()
+		}
+	}
+	for i, name := range sorted {
+		if i > 0 && name == sorted[i-1] {
+			continue
+		}
+		lock := m.rollupLock(name)
+		lock.Lock()
+		locks = append(locks, lock)
+	}
+	for _, name := range names {
+		if err := m.provisioning(name); err != nil {
+			unlock()
+			return nil, err
+		}
+	}
+	return unlock, nil
+}
+
+// withRollup runs op holding the lock of rollup name, unless the rollup is
+// being provisioned.
+func withRollup[T any](m *Manager, name string, op func() (T, error)) (T, error) {
+	unlock, err := m.lockRollups(name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer unlock()
+	return op()
+}
+
 func (m *Manager) Db() *db.LocalFileDatabase {
 	return m.db
 }
 
-func (m *Manager) CreateRollup(req *types.CreateRollupRequest) error {
-	rollup, err := l2.CreateRollup(context.Background(),
-		m.cfg,
-		req,
-		m.db)
+// CreateRollup starts provisioning in the background and returns its job.
+func (m *Manager) CreateRollup(req *types.CreateRollupRequest) (*types.RollupJob, error) {
+	unlock, err := m.lockRollups(req.Name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if _, err := m.db.GetRollupByName(req.Name); err == nil {
+		unlock()
+		return nil, fmt.Errorf("rollup %s already exists", req.Name)
+	}
+	if err := l2.ValidateSpec(req.Spec(), m.cfg); err != nil {
+		unlock()
+		return nil, err
 	}
-	err = l2.RunRollup(m.cfg, rollup, m.db)
+	job := m.startJob(req.Name)
+	go m.provision(req.Name, unlock, func() (*types.Rollup, error) {
+		return l2.CreateRollup(context.Background(), m.cfg, req, m.db)
+	})
+	return job, nil
+}
+
+// ForkRollup starts forking source in the background and returns the job of
+// the fork, holding the lock of source until the fork copied its state.
+func (m *Manager) ForkRollup(source string, req *types.ForkRollupRequest) (*types.RollupJob, error) {
+	if req.Name == source {
+		return nil, fmt.Errorf("rollup %s can not be forked into itself", source)
+	}
+	unlock, err := m.lockRollups(req.Name, source)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return err
+	spec, fork, err := l2.ForkSpec(context.Background(), m.cfg, source, req, m.db)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	// the source is released once its state is exported, the fork stays
+	// locked until it is provisioned
+	var released sync.Once
+	unlockSource := func() { released.Do(m.rollupLock(source).Unlock) }
+	unlockFork := func() {
+		unlockSource()
+		m.rollupLock(req.Name).Unlock()
+	}
+	job := m.startJob(req.Name)
+	go m.provision(req.Name, unlockFork, func() (*types.Rollup, error) {
+		return l2.ForkRollup(context.Background(), m.cfg, spec, fork, m.db, unlockSource)
+	})
+	return job, nil
+}
+
+// PlanRollup renders the artifacts of the request into the plans dir of the
+// build dir without deploying anything.
+func (m *Manager) PlanRollup(req *types.CreateRollupRequest) (*types.RollupPlan, error) {
+	// the name picks the dir the plan is rendered to
+	if err := types.ValidateRollupName(req.Name); err != nil {
+		return nil, err
+	}
+	outDir := path.Join(l2.BuildDir, "plans", req.Name)
+	return l2.PlanRollup(context.Background(), m.cfg, req.Spec(), m.db, outDir)
+}
+
+// startJob records the pending provision of rollup name.
+func (m *Manager) startJob(name string) *types.RollupJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := &types.RollupJob{
+		Name:   name,
+		Step:   types.RollupInit,
+		Status: "pending",
+	}
+	m.jobs[name] = job
+	return job.Copy()
+}
+
+// provision runs create, which records and renders the rollup, then brings
+// the rollup up and updates its job. It releases the locks of the rollup with
+// unlock once done.
+func (m *Manager) provision(name string, unlock func(), create func() (*types.Rollup, error)) {
+	defer unlock()
+	rollup, err := create()
+	if err == nil {
+		err = l2.RunRollup(m.cfg, rollup, m.db)
+	}
+	if err != nil {
+		log15.Error("failed to provision rollup", "name", name, "err", err)
+		if rollup != nil {
+			rollup.Error = err.Error()
+			m.db.UpdateRollup(rollup)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[name]
+	if rollup != nil {
+		job.Step = rollup.Step
+		job.Status = rollup.Status
+	}
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// RollupStatus reports the provisioning progress of a rollup, preferring the
+// stored record and falling back to the in-memory job before it is written.
+func (m *Manager) RollupStatus(name string) (*types.RollupJob, error) {
+	rollup, err := m.db.GetRollupByName(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[name]
+	if err != nil {
+		if ok {
+			return job.Copy(), nil
+		}
+		return nil, fmt.Errorf("rollup %s not found", name)
+	}
+	status := types.JobFromRollup(rollup)
+	if ok && job.Error != "" {
+		status.Error = job.Error
+	}
+	return status, nil
 }
 
+func (m *Manager) RollupLogs(name, service string, tail int) (string, error) {
+	if _, err := m.db.GetRollupByName(name); err != nil {
+		return "", fmt.Errorf("rollup %s not found", name)
+	}
+	return l2.RollupLogsByName(context.Background(), m.cfg, name, service, tail)
+}
+
+func (m *Manager) ProverStatus(name string) (*types.ProverStatus, error) {
+	rollup, err := m.db.GetRollupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("rollup %s not found", name)
+	}
+	return l2.RollupProverStatus(context.Background(), m.cfg, rollup), nil
+}
+
+// VerifyRollup reruns the end to end checks of a running rollup.
+func (m *Manager) VerifyRollup(name string) (*types.RollupVerification, error) {
+	verification, err := withRollup(m, name, func() (*types.RollupVerification, error) {
+		return l2.VerifyRollupByName(context.Background(), m.cfg, name, m.db)
+	})
+	if err == nil && verification.Passed {
+		// the error of a failed provisioning no longer applies
+		m.mu.Lock()
+		delete(m.jobs, name)
+		m.mu.Unlock()
+	}
+	return verification, err
+}
+
+// Faucet sends the faucet amount of a rollup to address, asked from ip.
+func (m *Manager) Faucet(name, address, ip string) (*types.FaucetDrip, error) {
+	rollup, err := m.db.GetRollupByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("rollup %s not found", name)
+	}
+	return m.faucets.Drip(context.Background(), rollup, address, ip)
+}
+
+// FundWallets adds or tops up funded wallets of a running rollup.
+func (m *Manager) FundWallets(name string, wallets []types.L2FundWallets) ([]types.WalletTransfer, error) {
+	return withRollup(m, name, func() ([]types.WalletTransfer, error) {
+		return l2.FundWallets(context.Background(), m.cfg, name, wallets, m.db)
+	})
+}
+
+func (m *Manager) WalletBalances(name string) ([]types.WalletBalance, error) {
+	return l2.WalletBalances(context.Background(), name, m.db)
+}
+
+// ChainMetadata describes a running rollup for wallets and chain registries.
+func (m *Manager) ChainMetadata(name string) (*types.ChainMetadata, error) {
+	rollup, err := m.db.GetRollupByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if rollup.RpcUrl == "" {
+		return nil, fmt.Errorf("rollup %s is not running yet", name)
+	}
+	return types.NewChainMetadata(rollup), nil
+}
+
+// Contracts lists the contracts of a rollup and checks their code on chain.
+func (m *Manager) Contracts(name string) (*types.RollupContractRegistry, error) {
+	return l2.ContractRegistry(context.Background(), m.cfg, name, m.db)
+}
+
+// ScaleReplicas changes the replica count of a provisioned rollup and waits
+// until the new topology serves rpc.
+func (m *Manager) ScaleReplicas(name string, replicas int) (*types.Rollup, error) {
+	return withRollup(m, name, func() (*types.Rollup, error) {
+		result, err := l2.ScaleReplicas(context.Background(), m.cfg, name, replicas, m.db)
+		if err != nil {
+			return nil, err
+		}
+		return result.Rollup, nil
+	})
+}
+
+func (m *Manager) RotateJwtSecret(name string) (*types.Rollup, error) {
+	return withRollup(m, name, func() (*types.Rollup, error) {
+		return l2.RotateJwtSecret(context.Background(), m.cfg, name, m.db)
+	})
+}
+
+func (m *Manager) UpgradeContracts(name string, version int) (*types.Rollup, error) {
+	return withRollup(m, name, func() (*types.Rollup, error) {
+		return l2.UpgradeRollupContracts(context.Background(), m.cfg, name, version, m.db)
+	})
+}
+
+func (m *Manager) UpgradeImage(name, image, version string) (*types.Rollup, error) {
+	return withRollup(m, name, func() (*types.Rollup, error) {
+		return l2.UpgradeRollupImage(context.Background(), m.cfg, name, image, version, m.db)
+	})
+}
+
+func (m *Manager) BackupRollup(name string) (*types.RollupBackup, error) {
+	return withRollup(m, name, func() (*types.RollupBackup, error) {
+		return l2.BackupRollup(context.Background(), m.cfg, name, m.db, m.volumes)
+	})
+}
+
+func (m *Manager) ListBackups(name string) ([]*types.RollupBackup, error) {
+	if _, err := m.db.GetRollupByName(name); err != nil {
+		return nil, fmt.Errorf("rollup %s not found", name)
+	}
+	return l2.ListBackups(name)
+}
+
+// RestoreRollup restores the archive at file under name, or under the name
+// it was backed up with when name is empty, holding the lock of that name.
+func (m *Manager) RestoreRollup(file, name string) (*types.Rollup, error) {
+	file, err := l2.BackupFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		backup, err := l2.ReadBackup(file)
+		if err != nil {
+			return nil, err
+		}
+		name = backup.Name
+	}
+	return withRollup(m, name, func() (*types.Rollup, error) {
+		return l2.RestoreRollup(context.Background(), m.cfg, file, name, m.db, m.volumes)
+	})
+}
+
+// TransitionRollup stops, starts or archives a provisioned rollup.
+func (m *Manager) TransitionRollup(name string, to int) (*types.Rollup, error) {
+	return withRollup(m, name, func() (*types.Rollup, error) {
+		switch to {
+		case types.Stopped:
+			return l2.StopRollup(context.Background(), m.cfg, name, m.db)
+		case types.Archived:
+			return l2.ArchiveRollup(context.Background(), m.cfg, name, m.db)
+		default:
+			return l2.StartRollup(context.Background(), m.cfg, name, m.db)
+		}
+	})
+}
+
+// DeleteRollup removes a rollup with its chain data. confirm must repeat
+// the name of the rollup.
 func (m *Manager) DeleteRollup(
 	name string,
+	confirm string,
 ) error {
-	err := l2.StopRollupByName(context.Background(), m.cfg, name, m.Db())
+	if confirm != name {
+		return fmt.Errorf("deleting rollup %s removes its chain data, confirm with its name or archive it instead", name)
+	}
+	_, err := withRollup(m, name, func() (struct{}, error) {
+		return struct{}{}, l2.DeleteRollupByName(context.Background(), m.cfg, name, m.Db())
+	})
 	return err
 }
@@ -8,23 +8,27 @@ fi
 
 echo 'starting node...'
 geth --datadir /data/{{.ChainName}}/l2_geth \
-    --networkid {{.ChainId}}\
+    --networkid {{.ChainId}} \
+    --nodekey /nodekey \
     --syncmode full \
     --txlookuplimit 0 \
-    --gcmode archive \
-    --miner.gasprice 0 \
-    --miner.gaslimit 50000000 \
+    --gcmode {{.GcMode}} \
+    --miner.gasprice {{.MinGasPrice}} \
+    --miner.gaslimit {{.GasLimit}} \
     --http \
     --http.addr 0.0.0.0 \
-    --http.vhosts "*" \
-    --http.api debug,eth,net,web3,txpool,miner,admin,rollup \
-    --http.corsdomain '*' \
+    --http.vhosts '{{.Vhosts}}' \
+    --http.api {{.Api}} \
+    --http.corsdomain '{{.CorsOrigins}}' \
     --ws \
     --ws.addr 0.0.0.0 \
-    --ws.origins '*' \
-    --ws.api debug,eth,net,web3,txpool,miner,admin,rollup \
+    --ws.origins '{{.CorsOrigins}}' \
+    --ws.api {{.Api}} \
     --authrpc.addr 0.0.0.0 \
+    --authrpc.jwtsecret {{.JwtSecretPath}} \
     --authrpc.port 8551 \
-    --authrpc.vhosts '*' \
+    --authrpc.vhosts '{{.AuthVhosts}}' \
+{{- if .AllowInsecureUnlock}}
     --allow-insecure-unlock \
-    --verbosity 3 \
+{{- end}}
+    --verbosity {{.Verbosity}}
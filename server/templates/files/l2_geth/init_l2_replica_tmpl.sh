@@ -0,0 +1,36 @@
+#!/bin/sh
+set -e
+
+# replica of the {{.ChainName}} sequencer, the first argument is its index
+DATADIR=/data/{{.ChainName}}/l2_replica_$1
+
+if [ ! -e $DATADIR/geth/chaindata ]; then
+    echo "init {{.ChainName}} replica $1"
+    geth init --datadir $DATADIR /l2_genesis.json
+fi
+
+echo 'starting replica...'
+geth --datadir $DATADIR \
+    --networkid {{.ChainId}} \
+    --bootnodes {{.SequencerEnode}} \
+    --syncmode full \
+    --txlookuplimit 0 \
+    --gcmode {{.GcMode}} \
+    --miner.gasprice {{.MinGasPrice}} \
+    --http \
+    --http.addr 0.0.0.0 \
+    --http.vhosts '{{.Vhosts}}' \
+    --http.api {{.Api}} \
+    --http.corsdomain '{{.CorsOrigins}}' \
+    --ws \
+    --ws.addr 0.0.0.0 \
+    --ws.origins '{{.CorsOrigins}}' \
+    --ws.api {{.Api}} \
+    --authrpc.addr 0.0.0.0 \
+    --authrpc.jwtsecret {{.JwtSecretPath}} \
+    --authrpc.port 8551 \
+    --authrpc.vhosts '{{.AuthVhosts}}' \
+{{- if .AllowInsecureUnlock}}
+    --allow-insecure-unlock \
+{{- end}}
+    --verbosity {{.Verbosity}}
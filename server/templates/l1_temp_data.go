@@ -0,0 +1,13 @@
+package templates
+
+type L1GethTemplateData struct {
+	GethImage    string
+	ChainId      int
+	MinerKey     string
+	MinerAddress string
+	HttpPort     int
+	WsPort       int
+	AuthPort     int
+	Explorer     bool
+	ExplorerPort int
+}
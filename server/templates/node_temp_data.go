@@ -1,6 +1,21 @@
 package templates
 
 type L2NodeTemplateData struct {
-	ChainId   int
-	ChainName string
+	ChainId             int
+	ChainName           string
+	GasLimit            uint64
+	MinGasPrice         string
+	GcMode              string
+	Api                 string
+	CorsOrigins         string
+	Vhosts              string
+	AuthVhosts          string
+	Verbosity           int
+	AllowInsecureUnlock bool
+	JwtSecretPath       string
+	SequencerEnode      string
+}
+
+type L2NodeDockerfileTempData struct {
+	Image string
 }
@@ -2,36 +2,68 @@ package templates
 
 import (
 	"bytes"
+	"embed"
 	"fmt"
-	"html/template"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
-
-	"github.com/inconshreveable/log15"
+	"text/template"
 )
 
-func Render(fullPath string, destDir string, temp interface{}) {
-	name := fullPath[strings.LastIndex(fullPath, "/")+1:]
-	dest := fmt.Sprintf("%s/%s", destDir, strings.ReplaceAll(name, "_tmpl", ""))
+//go:embed files
+var embedded embed.FS
+
+// Renderer renders the template files shipped with the binary. A template
+// with the same relative path in the override dir takes precedence.
+type Renderer struct {
+	overrideDir string
+}
 
-	if !strings.Contains(name, "_tmpl") {
-		log15.Crit("template name must contain _tmpl", "name", name)
-		panic("template name must contain _tmpl")
+func NewRenderer(overrideDir string) *Renderer {
+	return &Renderer{
+		overrideDir: overrideDir,
 	}
+}
 
-	t := template.Must(template.New(name).ParseFiles(fullPath))
-	var tpl bytes.Buffer
-	err := t.Execute(&tpl, temp)
-	if err != nil {
-		panic(err)
+func (r *Renderer) readTemplate(name string) ([]byte, error) {
+	if r.overrideDir != "" {
+		content, err := os.ReadFile(filepath.Join(r.overrideDir, name))
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
 	}
-	result := tpl.String()
+	return fs.ReadFile(embedded, path.Join("files", name))
+}
 
-	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0700)
-	if err != nil {
-		panic(err)
+// Render executes the template name, e.g. "l2_geth/init_l2_geth_tmpl.sh", and
+// writes it into destDir without the _tmpl marker. Missing keys are errors
+// and the result is validated before it is written.
+func (r *Renderer) Render(name string, destDir string, temp interface{}) error {
+	base := path.Base(name)
+	if !strings.Contains(base, "_tmpl") {
+		return fmt.Errorf("template name must contain _tmpl, got %s", name)
 	}
-	defer f.Close()
+	dest := filepath.Join(destDir, strings.ReplaceAll(base, "_tmpl", ""))
 
-	fmt.Fprint(f, result)
+	content, err := r.readTemplate(name)
+	if err != nil {
+		return fmt.Errorf("failed to read template %s, %w", name, err)
+	}
+	t, err := template.New(base).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s, %w", name, err)
+	}
+	var tpl bytes.Buffer
+	if err := t.Execute(&tpl, temp); err != nil {
+		return fmt.Errorf("failed to render template %s, %w", name, err)
+	}
+	if err := Validate(dest, tpl.Bytes()); err != nil {
+		return fmt.Errorf("rendered template %s is invalid, %w", name, err)
+	}
+	return os.WriteFile(dest, tpl.Bytes(), 0700)
 }
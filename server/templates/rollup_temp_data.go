@@ -5,4 +5,40 @@ type RollupTempData struct {
 	L2HttpPort    int
 	L2WsPort      int
 	L2AuthRpcPort int
+	// ConsensusRoles are passed to the consensus client as --enable-<role>.
+	ConsensusRoles []string
+	// ParamsDir is the host dir of the prover params, empty for the dummy prover.
+	ParamsDir  string
+	ParamsPath string
+	// JwtSecretFile is relative to the compose file and mounted read-only at
+	// JwtSecretPath.
+	JwtSecretFile string
+	JwtSecretPath string
+	// Replicas are the indexes of the replica nodes, starting at 1.
+	Replicas  []int
+	RpcLbPort int
+	// Prover is only set when the rollup runs a dedicated prover.
+	Prover *ProverTempData
+	// Explorer is only set when the rollup runs an explorer.
+	Explorer *ExplorerTempData
+}
+
+type ExplorerTempData struct {
+	ChainId int
+	Port    int
+	Network string
+	Label   string
+	Coin    string
+	Logo    string
+}
+
+type ProverTempData struct {
+	Image  string
+	Port   int
+	Cpus   string
+	Memory string
+}
+
+type RpcLbTempData struct {
+	Replicas []int
 }
@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var dockerfileInstructions = map[string]bool{
+	"ADD":         true,
+	"ARG":         true,
+	"CMD":         true,
+	"COPY":        true,
+	"ENTRYPOINT":  true,
+	"ENV":         true,
+	"EXPOSE":      true,
+	"FROM":        true,
+	"HEALTHCHECK": true,
+	"LABEL":       true,
+	"MAINTAINER":  true,
+	"ONBUILD":     true,
+	"RUN":         true,
+	"SHELL":       true,
+	"STOPSIGNAL":  true,
+	"USER":        true,
+	"VOLUME":      true,
+	"WORKDIR":     true,
+}
+
+// Validate checks a rendered artifact according to the kind of file it is
+// written to. Unknown kinds pass unchecked.
+func Validate(dest string, content []byte) error {
+	base := filepath.Base(dest)
+	switch {
+	case strings.HasSuffix(base, ".yaml"), strings.HasSuffix(base, ".yml"):
+		return validateYaml(content)
+	case strings.HasSuffix(base, ".sh"):
+		return validateShell(content)
+	case base == "Dockerfile" || strings.HasSuffix(base, ".Dockerfile"):
+		return validateDockerfile(content)
+	}
+	return nil
+}
+
+func validateYaml(content []byte) error {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("invalid yaml, %w", err)
+	}
+	if len(doc) == 0 {
+		return fmt.Errorf("yaml document is empty")
+	}
+	return nil
+}
+
+// validateShell runs the script through `sh -n`, which parses it without
+// executing anything.
+func validateShell(content []byte) error {
+	cmd := exec.Command("sh", "-n")
+	cmd.Stdin = bytes.NewReader(content)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("invalid shell script, %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func validateDockerfile(content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	seenFrom := false
+	continued := false
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if continued {
+			continued = strings.HasSuffix(line, "\\")
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		continued = strings.HasSuffix(line, "\\")
+		instruction := strings.ToUpper(strings.Fields(line)[0])
+		if !dockerfileInstructions[instruction] {
+			return fmt.Errorf("invalid dockerfile, unknown instruction %s on line %d", instruction, lineNo)
+		}
+		if !seenFrom && instruction != "FROM" && instruction != "ARG" {
+			return fmt.Errorf("invalid dockerfile, %s on line %d comes before FROM", instruction, lineNo)
+		}
+		if instruction == "FROM" {
+			if len(strings.Fields(line)) < 2 {
+				return fmt.Errorf("invalid dockerfile, FROM on line %d has no image", lineNo)
+			}
+			seenFrom = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !seenFrom {
+		return fmt.Errorf("invalid dockerfile, no FROM instruction")
+	}
+	return nil
+}
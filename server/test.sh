@@ -1,5 +1,5 @@
 #!/bin/sh
 set -e
 
-curl -X POST localhost:8080/api/v1/rollup/g1g2 -H 'Content-Type: application/json' \
- -d '{"name":"ethbeijing","chain_id":10405,"beneficial":"0x4331e30d6d8201319D80f6FdB063Ca376114F203"}'
+go run cmd/* rollup create --server http://127.0.0.1:8080 \
+ --chain-id 10405 --beneficiary 0x4331e30d6d8201319D80f6FdB063Ca376114F203 --wait ethbeijing
@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// RollupBackup describes a backup archive of a rollup. The archive holds the
+// rollup record, its contract record, the rendered build dir and a tar of
+// each listed docker volume.
+type RollupBackup struct {
+	Name      string    `json:"name"`
+	File      string    `json:"file"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size,omitempty"`
+	Volumes   []string  `json:"volumes"`
+}
+
+type RestoreRollupRequest struct {
+	// File is an archive in the backups dir of the rollup server, as listed
+	// for the rollup or relative to that dir.
+	File string `json:"file" validate:"required"`
+	// Name restores the rollup under another name, empty keeps the one it
+	// was backed up with.
+	Name string `json:"name,omitempty"`
+}
+
+func (r *RestoreRollupRequest) Validate() error {
+	if r.Name == "" {
+		return nil
+	}
+	return ValidateRollupName(r.Name)
+}
@@ -0,0 +1,145 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var DefaultNativeCurrency = NativeCurrency{
+	Name:     "Ether",
+	Symbol:   "ETH",
+	Decimals: 18,
+}
+
+// NativeCurrency is the coin a rollup pays its gas in, as wallets show it.
+type NativeCurrency struct {
+	Name     string `yaml:"name" json:"name"`
+	Symbol   string `yaml:"symbol" json:"symbol"`
+	Decimals int    `yaml:"decimals" json:"decimals"`
+}
+
+// Merge fills the empty fields of c from defaults.
+func (c NativeCurrency) Merge(defaults NativeCurrency) NativeCurrency {
+	if c.Name == "" {
+		c.Name = defaults.Name
+	}
+	if c.Symbol == "" {
+		c.Symbol = defaults.Symbol
+	}
+	if c.Decimals == 0 {
+		c.Decimals = defaults.Decimals
+	}
+	return c
+}
+
+// Validate applies the limits of EIP-3085 wallets.
+func (c *NativeCurrency) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("native_currency name is required")
+	}
+	if len(c.Symbol) < 2 || len(c.Symbol) > 6 {
+		return fmt.Errorf("native_currency symbol must have 2 to 6 characters, got %q", c.Symbol)
+	}
+	if c.Decimals <= 0 || c.Decimals > 36 {
+		return fmt.Errorf("native_currency decimals must be between 1 and 36, got %d", c.Decimals)
+	}
+	return nil
+}
+
+// ValidateIcon checks the icon of a rollup is an http, https or ipfs url.
+func ValidateIcon(icon string) error {
+	if icon == "" {
+		return nil
+	}
+	u, err := url.Parse(icon)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ipfs") {
+		return fmt.Errorf("icon must be an http, https or ipfs url, got %q", icon)
+	}
+	return nil
+}
+
+// AddEthereumChain is the parameter of the EIP-3085 wallet_addEthereumChain
+// call.
+type AddEthereumChain struct {
+	ChainId           string         `json:"chainId"`
+	ChainName         string         `json:"chainName"`
+	NativeCurrency    NativeCurrency `json:"nativeCurrency"`
+	RpcUrls           []string       `json:"rpcUrls"`
+	BlockExplorerUrls []string       `json:"blockExplorerUrls,omitempty"`
+	IconUrls          []string       `json:"iconUrls,omitempty"`
+}
+
+// ChainListEntry is a chain as listed in ethereum-lists/chains.
+type ChainListEntry struct {
+	Name           string          `json:"name"`
+	Chain          string          `json:"chain"`
+	Icon           string          `json:"icon,omitempty"`
+	Rpc            []string        `json:"rpc"`
+	Faucets        []string        `json:"faucets"`
+	NativeCurrency NativeCurrency  `json:"nativeCurrency"`
+	InfoURL        string          `json:"infoURL"`
+	ShortName      string          `json:"shortName"`
+	ChainId        int             `json:"chainId"`
+	NetworkId      int             `json:"networkId"`
+	Explorers      []ChainExplorer `json:"explorers,omitempty"`
+	Parent         *ChainParent    `json:"parent,omitempty"`
+}
+
+type ChainExplorer struct {
+	Name     string `json:"name"`
+	Url      string `json:"url"`
+	Standard string `json:"standard"`
+}
+
+// ChainParent is the l1 a rollup settles on, as eip155-<chain id>.
+type ChainParent struct {
+	Type  string `json:"type"`
+	Chain string `json:"chain"`
+}
+
+// ChainMetadata describes a rollup for wallets and chain registries.
+type ChainMetadata struct {
+	Wallet    AddEthereumChain `json:"wallet"`
+	ChainList ChainListEntry   `json:"chain_list"`
+}
+
+// NewChainMetadata builds the chain metadata of a running rollup from its
+// record.
+func NewChainMetadata(rollup *Rollup) *ChainMetadata {
+	currency := rollup.NativeCurrency.Merge(DefaultNativeCurrency)
+	wallet := AddEthereumChain{
+		ChainId:        fmt.Sprintf("0x%x", rollup.ChainId),
+		ChainName:      rollup.Name,
+		NativeCurrency: currency,
+		RpcUrls:        []string{rollup.RpcUrl},
+	}
+	entry := ChainListEntry{
+		Name:           rollup.Name,
+		Chain:          currency.Symbol,
+		Icon:           rollup.Icon,
+		Rpc:            []string{rollup.RpcUrl},
+		Faucets:        []string{},
+		NativeCurrency: currency,
+		InfoURL:        rollup.ExplorerUrl,
+		ShortName:      strings.ToLower(rollup.Name),
+		ChainId:        rollup.ChainId,
+		NetworkId:      rollup.ChainId,
+		Parent: &ChainParent{
+			Type:  "L2",
+			Chain: fmt.Sprintf("eip155-%d", rollup.L1.ChainId),
+		},
+	}
+	if rollup.ExplorerUrl != "" {
+		wallet.BlockExplorerUrls = []string{rollup.ExplorerUrl}
+		entry.Explorers = []ChainExplorer{{
+			Name:     rollup.Explorer.Network,
+			Url:      rollup.ExplorerUrl,
+			Standard: "EIP3091",
+		}}
+	}
+	if rollup.Icon != "" {
+		wallet.IconUrls = []string{rollup.Icon}
+	}
+	return &ChainMetadata{Wallet: wallet, ChainList: entry}
+}
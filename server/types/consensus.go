@@ -0,0 +1,175 @@
+package types
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const (
+	RolePropose = "propose"
+	RoleProve   = "prove"
+	RoleRelay   = "relay"
+	RoleSync    = "sync"
+
+	ProverDummy = "dummy"
+	ProverReal  = "real"
+)
+
+var (
+	cpusPattern   = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+	memoryPattern = regexp.MustCompile(`^[0-9]+[bkmg]?$`)
+
+	consensusRoles = map[string]bool{
+		RolePropose: true,
+		RoleProve:   true,
+		RoleRelay:   true,
+		RoleSync:    true,
+	}
+
+	DefaultConsensusSpec = ConsensusSpec{
+		ProposeInterval: 2,
+		Roles:           []string{RolePropose, RoleProve, RoleRelay, RoleSync},
+		Prover: ProverSpec{
+			Mode:        ProverDummy,
+			CircuitName: "dummy",
+		},
+	}
+)
+
+// ResourceSpec limits the container of a service, in the units of docker
+// compose: cpus like 0.5 and memory like 8g.
+type ResourceSpec struct {
+	Cpus   string `yaml:"cpus" json:"cpus,omitempty"`
+	Memory string `yaml:"memory" json:"memory,omitempty"`
+}
+
+func (r *ResourceSpec) Validate() error {
+	if r.Cpus != "" && !cpusPattern.MatchString(r.Cpus) {
+		return fmt.Errorf("invalid cpus limit %q", r.Cpus)
+	}
+	if r.Memory != "" && !memoryPattern.MatchString(r.Memory) {
+		return fmt.Errorf("invalid memory limit %q", r.Memory)
+	}
+	return nil
+}
+
+// ProverSpec selects the dummy prover or a real one. A real prover reads the
+// circuit params from ParamsDir on the host, mounted read-only. It runs as a
+// prover-rpcd service of the rollup limited by Resources, unless Pool names
+// a shared prover pool of the server config.
+type ProverSpec struct {
+	Mode        string       `yaml:"mode" json:"mode,omitempty"`
+	CircuitName string       `yaml:"circuit_name" json:"circuit_name,omitempty"`
+	ParamsDir   string       `yaml:"params_dir" json:"params_dir,omitempty"`
+	Pool        string       `yaml:"pool" json:"pool,omitempty"`
+	Resources   ResourceSpec `yaml:"resources" json:"resources,omitempty"`
+}
+
+// Dedicated reports whether the rollup runs its own prover-rpcd service.
+func (p *ProverSpec) Dedicated() bool {
+	return p.Mode == ProverReal && p.Pool == ""
+}
+
+// ConsensusSpec configures the consensus client. Empty fields take the value
+// of the server config, then of DefaultConsensusSpec.
+type ConsensusSpec struct {
+	RelayDbUrl      string     `yaml:"relay_db_url" json:"relay_db_url,omitempty"`
+	ProposeInterval int        `yaml:"propose_interval" json:"propose_interval,omitempty"`
+	Roles           []string   `yaml:"roles" json:"roles,omitempty"`
+	Prover          ProverSpec `yaml:"prover" json:"prover"`
+}
+
+func (c ConsensusSpec) Merge(defaults ConsensusSpec) ConsensusSpec {
+	if c.RelayDbUrl == "" {
+		c.RelayDbUrl = defaults.RelayDbUrl
+	}
+	if c.ProposeInterval == 0 {
+		c.ProposeInterval = defaults.ProposeInterval
+	}
+	if len(c.Roles) == 0 {
+		c.Roles = defaults.Roles
+	}
+	if c.Prover.Mode == "" {
+		c.Prover.Mode = defaults.Prover.Mode
+	}
+	if c.Prover.CircuitName == "" {
+		c.Prover.CircuitName = defaults.Prover.CircuitName
+	}
+	if c.Prover.ParamsDir == "" {
+		c.Prover.ParamsDir = defaults.Prover.ParamsDir
+	}
+	if c.Prover.Pool == "" {
+		c.Prover.Pool = defaults.Prover.Pool
+	}
+	if c.Prover.Resources.Cpus == "" {
+		c.Prover.Resources.Cpus = defaults.Prover.Resources.Cpus
+	}
+	if c.Prover.Resources.Memory == "" {
+		c.Prover.Resources.Memory = defaults.Prover.Resources.Memory
+	}
+	return c
+}
+
+func (c *ConsensusSpec) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the combination of roles and prover settings and sorts
+// the roles.
+func (c *ConsensusSpec) Validate() error {
+	if c.ProposeInterval <= 0 {
+		return fmt.Errorf("consensus propose_interval must be positive, got %d", c.ProposeInterval)
+	}
+	seen := map[string]bool{}
+	for _, role := range c.Roles {
+		if !consensusRoles[role] {
+			return fmt.Errorf("unknown consensus role %q", role)
+		}
+		if seen[role] {
+			return fmt.Errorf("consensus role %s listed twice", role)
+		}
+		seen[role] = true
+	}
+	c.Roles = append([]string{}, c.Roles...)
+	sort.Strings(c.Roles)
+	// the consensus client is the only driver of the l2 node's engine api
+	if !seen[RoleSync] {
+		return fmt.Errorf("consensus role %s is required", RoleSync)
+	}
+
+	switch c.Prover.Mode {
+	case ProverDummy:
+		if c.Prover.ParamsDir != "" {
+			return fmt.Errorf("consensus prover params_dir is only used by the %s prover", ProverReal)
+		}
+		if c.Prover.Pool != "" {
+			return fmt.Errorf("consensus prover pool is only used by the %s prover", ProverReal)
+		}
+	case ProverReal:
+		if !seen[RoleProve] {
+			return fmt.Errorf("consensus prover %s requires the %s role", ProverReal, RoleProve)
+		}
+		if c.Prover.CircuitName == "" || c.Prover.CircuitName == "dummy" {
+			return fmt.Errorf("consensus prover %s requires a circuit_name", ProverReal)
+		}
+		if c.Prover.ParamsDir == "" {
+			return fmt.Errorf("consensus prover %s requires params_dir", ProverReal)
+		}
+		if !filepath.IsAbs(c.Prover.ParamsDir) {
+			return fmt.Errorf("consensus prover params_dir must be an absolute path, got %s", c.Prover.ParamsDir)
+		}
+		if err := c.Prover.Resources.Validate(); err != nil {
+			return fmt.Errorf("consensus prover %w", err)
+		}
+	default:
+		return fmt.Errorf("consensus prover mode must be %s or %s, got %q", ProverDummy, ProverReal, c.Prover.Mode)
+	}
+	return nil
+}
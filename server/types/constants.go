@@ -5,4 +5,5 @@ const (
 	L2BridgeAddr         = "0x4200000000000000000000000000000000000003"
 	L2EscrowAddr         = "0x4200000000000000000000000000000000000004"
 	L2AddressManagerAddr = "0x4200000000000000000000000000000000000001"
+	L2ProxyAdminAddr     = "0x4200000000000000000000000000000000000000"
 )
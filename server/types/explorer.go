@@ -0,0 +1,75 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+var DefaultExplorerSpec = ExplorerSpec{
+	Coin: "ETH",
+	Logo: "/images/g1g2.svg",
+}
+
+// ExplorerSpec configures the blockscout explorer of a rollup. The network
+// name and label default to the name of the rollup and the coin to the
+// symbol of its native currency, the other empty fields take the value of the
+// server config, then of DefaultExplorerSpec.
+type ExplorerSpec struct {
+	Enabled *bool  `yaml:"enabled" json:"enabled,omitempty"`
+	Network string `yaml:"network" json:"network,omitempty"`
+	// Label is the testnet label shown next to the logo.
+	Label string `yaml:"label" json:"label,omitempty"`
+	Coin  string `yaml:"coin" json:"coin,omitempty"`
+	// Logo is a url or a path of the blockscout image.
+	Logo string `yaml:"logo" json:"logo,omitempty"`
+	// Port is the host port of the explorer, ports.base+5 by default.
+	Port int `yaml:"port" json:"port,omitempty"`
+}
+
+// Merge fills the empty fields of e from defaults.
+func (e ExplorerSpec) Merge(defaults ExplorerSpec) ExplorerSpec {
+	if e.Enabled == nil {
+		e.Enabled = defaults.Enabled
+	}
+	if e.Network == "" {
+		e.Network = defaults.Network
+	}
+	if e.Label == "" {
+		e.Label = defaults.Label
+	}
+	if e.Coin == "" {
+		e.Coin = defaults.Coin
+	}
+	if e.Logo == "" {
+		e.Logo = defaults.Logo
+	}
+	if e.Port == 0 {
+		e.Port = defaults.Port
+	}
+	return e
+}
+
+func (e *ExplorerSpec) IsEnabled() bool {
+	return e.Enabled != nil && *e.Enabled
+}
+
+func (e *ExplorerSpec) Validate() error {
+	// the values are rendered quoted into the compose file
+	for name, value := range map[string]string{
+		"network": e.Network,
+		"label":   e.Label,
+		"coin":    e.Coin,
+		"logo":    e.Logo,
+	} {
+		if strings.ContainsAny(value, "'\"\n\r\\") {
+			return fmt.Errorf("explorer %s must not contain quotes, backslashes or newlines, got %q", name, value)
+		}
+	}
+	if e.Coin == "" {
+		return fmt.Errorf("explorer coin is required")
+	}
+	if e.Port < 0 || e.Port > 65535 {
+		return fmt.Errorf("explorer port must be between 1 and 65535, got %d", e.Port)
+	}
+	return nil
+}
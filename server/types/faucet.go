@@ -0,0 +1,106 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var DefaultFaucetSpec = FaucetSpec{
+	PremintWei:      "1000000000000000000000000",
+	AmountWei:       "1000000000000000000",
+	AddressCooldown: seconds(3600),
+	IpCooldown:      seconds(600),
+	DailyCapWei:     "100000000000000000000",
+}
+
+// FaucetSpec configures the faucet of a rollup, an account premined in the
+// genesis that sends AmountWei to whoever asks. Empty fields take the value
+// of the server config, then of DefaultFaucetSpec. A faucet is enabled by
+// default on an l1 g1g2 runs itself only, production rollups opt in.
+type FaucetSpec struct {
+	Enabled    *bool  `yaml:"enabled" json:"enabled,omitempty"`
+	PremintWei string `yaml:"premint_wei" json:"premint_wei,omitempty"`
+	AmountWei  string `yaml:"amount_wei" json:"amount_wei,omitempty"`
+	// AddressCooldown and IpCooldown are the seconds before the same address
+	// or ip is served again, 0 turns the cooldown off.
+	AddressCooldown *int `yaml:"address_cooldown" json:"address_cooldown,omitempty"`
+	IpCooldown      *int `yaml:"ip_cooldown" json:"ip_cooldown,omitempty"`
+	// DailyCapWei bounds what the faucet sends per UTC day.
+	DailyCapWei string `yaml:"daily_cap_wei" json:"daily_cap_wei,omitempty"`
+}
+
+// Merge fills the empty fields of f from defaults.
+func (f FaucetSpec) Merge(defaults FaucetSpec) FaucetSpec {
+	if f.Enabled == nil {
+		f.Enabled = defaults.Enabled
+	}
+	if f.PremintWei == "" {
+		f.PremintWei = defaults.PremintWei
+	}
+	if f.AmountWei == "" {
+		f.AmountWei = defaults.AmountWei
+	}
+	if f.AddressCooldown == nil {
+		f.AddressCooldown = defaults.AddressCooldown
+	}
+	if f.IpCooldown == nil {
+		f.IpCooldown = defaults.IpCooldown
+	}
+	if f.DailyCapWei == "" {
+		f.DailyCapWei = defaults.DailyCapWei
+	}
+	return f
+}
+
+func (f *FaucetSpec) IsEnabled() bool {
+	return f.Enabled != nil && *f.Enabled
+}
+
+// Cooldowns returns the address and ip cooldowns, unset ones are off.
+func (f *FaucetSpec) Cooldowns() (address, ip time.Duration) {
+	if f.AddressCooldown != nil {
+		address = time.Duration(*f.AddressCooldown) * time.Second
+	}
+	if f.IpCooldown != nil {
+		ip = time.Duration(*f.IpCooldown) * time.Second
+	}
+	return address, ip
+}
+
+func seconds(n int) *int {
+	return &n
+}
+
+func (f *FaucetSpec) Validate() error {
+	amounts := []struct {
+		name  string
+		value string
+	}{
+		{"premint_wei", f.PremintWei},
+		{"amount_wei", f.AmountWei},
+		{"daily_cap_wei", f.DailyCapWei},
+	}
+	for _, a := range amounts {
+		if v, ok := new(big.Int).SetString(a.value, 10); !ok || v.Sign() <= 0 {
+			return fmt.Errorf("faucet %s must be a positive amount in wei, got %q", a.name, a.value)
+		}
+	}
+	if address, ip := f.Cooldowns(); address < 0 || ip < 0 {
+		return fmt.Errorf("faucet cooldowns must not be negative")
+	}
+	return nil
+}
+
+type FaucetRequest struct {
+	Address string `json:"address" validate:"required"`
+}
+
+// FaucetDrip is a transfer of the faucet, sent but not necessarily included
+// yet.
+type FaucetDrip struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	AmountWei string `json:"amount_wei"`
+	TxHash    string `json:"tx_hash"`
+}
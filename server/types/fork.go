@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// ForkRollupRequest forks a rollup into a new one at Block, the head of the
+// source when it is omitted.
+type ForkRollupRequest struct {
+	Name     string  `json:"name" validate:"required"`
+	ChainId  int     `json:"chain_id" validate:"required"`
+	Block    *uint64 `json:"block,omitempty"`
+	BasePort int     `json:"base_port,omitempty"`
+}
+
+func (r *ForkRollupRequest) Validate() error {
+	return ValidateRollupName(r.Name)
+}
+
+// RollupFork is the provenance of a forked rollup.
+type RollupFork struct {
+	Source        string    `json:"source"`
+	SourceChainId int       `json:"source_chain_id"`
+	Block         uint64    `json:"block"`
+	BlockHash     string    `json:"block_hash"`
+	StateRoot     string    `json:"state_root"`
+	Accounts      int       `json:"accounts"`
+	ForkedAt      time.Time `json:"forked_at"`
+}
@@ -0,0 +1,27 @@
+package types
+
+type RollupJob struct {
+	Name   string `json:"name"`
+	Step   int    `json:"step"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func JobFromRollup(rollup *Rollup) *RollupJob {
+	return &RollupJob{
+		Name:   rollup.Name,
+		Step:   rollup.Step,
+		Status: rollup.Status,
+		Error:  rollup.Error,
+	}
+}
+
+// Done reports whether provisioning finished, successfully or not.
+func (j *RollupJob) Done() bool {
+	return j.Step == Online || j.Error != ""
+}
+
+func (j *RollupJob) Copy() *RollupJob {
+	job := *j
+	return &job
+}
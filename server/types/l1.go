@@ -8,6 +8,9 @@ type L1Net struct {
 	InternalRpcUrl string `yaml:"internal_rpc" json:"internal_rpc"`
 	InternalWsUrl  string `yaml:"internal_ws" json:"internal_ws"`
 	ExplorerUrl    string `yaml:"explorer" json:"explorer"`
+	// Provider runs the l1: docker, anvil, geth-dev or external. An empty
+	// provider is external unless the l1 is the dev l1 of the server.
+	Provider string `yaml:"provider" json:"provider,omitempty"`
 }
 
 var (
@@ -19,5 +22,6 @@ var (
 		InternalRpcUrl: "http://host.docker.internal:10545",
 		InternalWsUrl:  "ws://host.docker.internal:10546",
 		ExplorerUrl:    "http://localhost:4001",
+		Provider:       "docker",
 	}
 )
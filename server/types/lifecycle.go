@@ -0,0 +1,43 @@
+package types
+
+import "fmt"
+
+var (
+	stepNames = map[int]string{
+		RollupInit:          "init",
+		RollupDeployOnL1:    "deploy on l1",
+		BuildExecutionImage: "build execution image",
+		BuildSequencerImage: "build sequencer image",
+		WaitItOnline:        "wait it online",
+		Online:              "online",
+		Stopped:             "stopped",
+		Archived:            "archived",
+	}
+
+	// stepTransitions are the lifecycle steps a provisioned rollup can move
+	// to. A stopped rollup keeps its containers, an archived one only keeps
+	// its data volumes and contract record.
+	stepTransitions = map[int][]int{
+		Online:   {Stopped, Archived},
+		Stopped:  {Online, Archived},
+		Archived: {Online},
+	}
+)
+
+func StepName(step int) string {
+	if name, ok := stepNames[step]; ok {
+		return name
+	}
+	return fmt.Sprintf("step %d", step)
+}
+
+// CheckStepTransition returns an error unless a rollup at step from may move
+// to step to.
+func CheckStepTransition(from, to int) error {
+	for _, step := range stepTransitions[from] {
+		if step == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("rollup can not go from %s to %s", StepName(from), StepName(to))
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+const (
+	GcModeArchive = "archive"
+	GcModeFull    = "full"
+)
+
+var (
+	namespacePattern = regexp.MustCompile(`^[a-z0-9]+$`)
+	originPattern    = regexp.MustCompile(`^[A-Za-z0-9*:/._-]+$`)
+	vhostPattern     = regexp.MustCompile(`^[A-Za-z0-9*._-]+$`)
+
+	// AdminNamespaces can control or inspect the node and are only exposed
+	// when the profile opts in with AdminApi.
+	AdminNamespaces = map[string]bool{
+		"admin":    true,
+		"debug":    true,
+		"miner":    true,
+		"personal": true,
+		"txpool":   true,
+	}
+
+	DefaultNodeProfile = NodeProfile{
+		GasLimit:    50000000,
+		MinGasPrice: "0",
+		GcMode:      GcModeArchive,
+		Api:         []string{"eth", "net", "web3", "rollup"},
+		CorsOrigins: []string{"http://localhost"},
+		Vhosts:      []string{"localhost"},
+		Verbosity:   3,
+	}
+)
+
+// NodeProfile configures the l2 execution node. Empty fields take the value
+// of the server config, then of DefaultNodeProfile.
+type NodeProfile struct {
+	GasLimit    uint64   `yaml:"gas_limit" json:"gas_limit,omitempty"`
+	MinGasPrice string   `yaml:"min_gas_price" json:"min_gas_price,omitempty"`
+	GcMode      string   `yaml:"gc_mode" json:"gc_mode,omitempty"`
+	Api         []string `yaml:"api" json:"api,omitempty"`
+	CorsOrigins []string `yaml:"cors_origins" json:"cors_origins,omitempty"`
+	// Vhosts are the host names the node serves http rpc for, besides its
+	// ip addresses and the names the containers of the rollup reach it by.
+	// A public rollup lists its public host.
+	Vhosts    []string `yaml:"vhosts" json:"vhosts,omitempty"`
+	Verbosity int      `yaml:"verbosity" json:"verbosity,omitempty"`
+	AdminApi  bool     `yaml:"admin_api" json:"admin_api,omitempty"`
+}
+
+// Merge fills the empty fields of p from defaults. AdminApi is enabled when
+// either side opts in.
+func (p NodeProfile) Merge(defaults NodeProfile) NodeProfile {
+	if p.GasLimit == 0 {
+		p.GasLimit = defaults.GasLimit
+	}
+	if p.MinGasPrice == "" {
+		p.MinGasPrice = defaults.MinGasPrice
+	}
+	if p.GcMode == "" {
+		p.GcMode = defaults.GcMode
+	}
+	if len(p.Api) == 0 {
+		p.Api = defaults.Api
+	}
+	if len(p.CorsOrigins) == 0 {
+		p.CorsOrigins = defaults.CorsOrigins
+	}
+	if len(p.Vhosts) == 0 {
+		p.Vhosts = defaults.Vhosts
+	}
+	if p.Verbosity == 0 {
+		p.Verbosity = defaults.Verbosity
+	}
+	p.AdminApi = p.AdminApi || defaults.AdminApi
+	return p
+}
+
+func (p *NodeProfile) Validate() error {
+	if p.GasLimit == 0 {
+		return fmt.Errorf("node gas_limit must be positive")
+	}
+	if price, ok := new(big.Int).SetString(p.MinGasPrice, 10); !ok || price.Sign() < 0 {
+		return fmt.Errorf("node min_gas_price must be a non-negative amount in wei, got %q", p.MinGasPrice)
+	}
+	if p.GcMode != GcModeArchive && p.GcMode != GcModeFull {
+		return fmt.Errorf("node gc_mode must be %s or %s, got %q", GcModeArchive, GcModeFull, p.GcMode)
+	}
+	for _, ns := range p.Api {
+		if !namespacePattern.MatchString(ns) {
+			return fmt.Errorf("invalid node api namespace %q", ns)
+		}
+		if AdminNamespaces[ns] && !p.AdminApi {
+			return fmt.Errorf("node api namespace %s requires admin_api", ns)
+		}
+	}
+	for _, origin := range p.CorsOrigins {
+		if !originPattern.MatchString(origin) {
+			return fmt.Errorf("invalid node cors origin %q", origin)
+		}
+	}
+	for _, vhost := range p.Vhosts {
+		if !vhostPattern.MatchString(vhost) {
+			return fmt.Errorf("invalid node vhost %q", vhost)
+		}
+	}
+	if p.Verbosity < 0 || p.Verbosity > 5 {
+		return fmt.Errorf("node verbosity must be between 0 and 5, got %d", p.Verbosity)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package types
+
+type PlannedContract struct {
+	Layer    string `json:"layer"`
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Deployed bool   `json:"deployed"`
+}
+
+type PlannedImage struct {
+	Service string `json:"service"`
+	Image   string `json:"image"`
+	Build   bool   `json:"build"`
+}
+
+type PlannedPort struct {
+	Service       string `json:"service"`
+	HostPort      string `json:"host_port"`
+	ContainerPort string `json:"container_port"`
+}
+
+// RollupPlan describes what provisioning a rollup would do without doing it.
+type RollupPlan struct {
+	Rollup    *Rollup           `json:"rollup"`
+	OutDir    string            `json:"out_dir"`
+	Artifacts []string          `json:"artifacts"`
+	Contracts []PlannedContract `json:"contracts"`
+	Images    []PlannedImage    `json:"images"`
+	Ports     []PlannedPort     `json:"ports"`
+}
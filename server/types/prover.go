@@ -0,0 +1,24 @@
+package types
+
+const (
+	ProverDeploymentNone      = "none"
+	ProverDeploymentDedicated = "dedicated"
+	ProverDeploymentPool      = "pool"
+)
+
+// ProverStatus reports the prover of a rollup. ProofLag is the number of l2
+// blocks between the head and the latest block the prover has a proof for.
+type ProverStatus struct {
+	Name              string `json:"name"`
+	Mode              string `json:"mode"`
+	Deployment        string `json:"deployment"`
+	Pool              string `json:"pool,omitempty"`
+	Url               string `json:"url,omitempty"`
+	Online            bool   `json:"online"`
+	Error             string `json:"error,omitempty"`
+	PendingTasks      int    `json:"pending_tasks"`
+	FailedTasks       int    `json:"failed_tasks"`
+	L2Head            uint64 `json:"l2_head"`
+	LatestProvenBlock uint64 `json:"latest_proven_block"`
+	ProofLag          uint64 `json:"proof_lag"`
+}
@@ -1,12 +1,24 @@
 package types
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"regexp"
 	"time"
 )
 
+// rollupNamePattern keeps rollup names usable as dir, volume and container
+// names.
+var rollupNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateRollupName refuses names that are not of lowercase letters, digits,
+// _ and -, or that start with _ or -.
+func ValidateRollupName(name string) error {
+	if !rollupNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid rollup name %q, use lowercase letters, digits, _ and -", name)
+	}
+	return nil
+}
+
 // deploy step
 const (
 	// deploy step
@@ -16,11 +28,18 @@ const (
 	BuildSequencerImage = 3
 	WaitItOnline        = 4
 	Online              = 5
+	// lifecycle of a provisioned rollup, see CheckStepTransition
+	Stopped  = 6
+	Archived = 7
 )
 
 type L2FundWallets struct {
-	WalletAddress string `json:"address,omitempty" yaml:"WalletAddress"`
-	AmountInWei   string `json:"amount,omitempty" yaml:"AmountInWei"`
+	WalletAddress string `json:"address,omitempty" yaml:"address"`
+	// AmountInWei is the genesis allocation, empty for wallets added to a
+	// running rollup.
+	AmountInWei string `json:"amount,omitempty" yaml:"amount"`
+	// Transfers funded the wallet on the running rollup.
+	Transfers []WalletTransfer `json:"transfers,omitempty" yaml:"-"`
 }
 
 type CreateRollupRequest struct {
@@ -28,41 +47,79 @@ type CreateRollupRequest struct {
 	ChainId            int             `json:"chain_id" validate:"required"`
 	BeneficiaryAddress string          `json:"beneficial"`
 	L2FundWallets      []L2FundWallets `json:"l2_wallets,omitempty"`
+	Node               NodeProfile     `json:"node"`
+	Consensus          ConsensusSpec   `json:"consensus"`
+	Replicas           int             `json:"replicas,omitempty"`
+	Faucet             FaucetSpec      `json:"faucet"`
+	Explorer           ExplorerSpec    `json:"explorer"`
+	// Icon and NativeCurrency are shown by wallets and chain registries.
+	Icon           string         `json:"icon,omitempty"`
+	NativeCurrency NativeCurrency `json:"native_currency"`
+}
+
+func (r *CreateRollupRequest) Validate() error {
+	return ValidateRollupName(r.Name)
+}
+
+type ScaleReplicasRequest struct {
+	Replicas int `json:"replicas"`
 }
 
 type Rollup struct {
-	Name               string          `json:"name" validate:"required"`
-	ChainId            int             `json:"chain_id" validate:"required"`
-	RpcUrl             string          `json:"rpc_url" validate:"required"`
-	L1Rollup           string          `json:"l1_rollup" validate:"required"`
-	L2Rollup           string          `json:"l2_rollup" validate:"required"`
-	L1Bridge           string          `json:"l1_bridge" validate:"required"`
-	L2Bridge           string          `json:"l2_bridge" validate:"required"`
-	L1Escrow           string          `json:"l1_escrow" validate:"required"`
-	L2Escrow           string          `json:"l2_escrow" validate:"required"`
-	L1AddressManager   string          `json:"l1_address_manager" validate:"required"`
-	L2AddressManager   string          `json:"l2_address_manager" validate:"required"`
-	ExecutionImage     string          `json:"execution_img" validate:"required"`
-	ConsensusImage     string          `json:"consensus_img" validate:"required"`
+	Name             string `json:"name" validate:"required"`
+	ChainId          int    `json:"chain_id" validate:"required"`
+	RpcUrl           string `json:"rpc_url" validate:"required"`
+	L1Rollup         string `json:"l1_rollup" validate:"required"`
+	L2Rollup         string `json:"l2_rollup" validate:"required"`
+	L1Bridge         string `json:"l1_bridge" validate:"required"`
+	L2Bridge         string `json:"l2_bridge" validate:"required"`
+	L1Escrow         string `json:"l1_escrow" validate:"required"`
+	L2Escrow         string `json:"l2_escrow" validate:"required"`
+	L1AddressManager string `json:"l1_address_manager" validate:"required"`
+	L2AddressManager string `json:"l2_address_manager" validate:"required"`
+	ExecutionImage   string `json:"execution_img" validate:"required"`
+	ConsensusImage   string `json:"consensus_img" validate:"required"`
+	ProverImage      string `json:"prover_img,omitempty"`
+	// The digests the image tags resolved to when the rollup was first rendered
+	// with them. Rendered artifacts pin the images to them.
+	ExecutionDigest    string          `json:"execution_digest,omitempty"`
+	ConsensusDigest    string          `json:"consensus_digest,omitempty"`
+	ProverDigest       string          `json:"prover_digest,omitempty"`
 	CreatedAt          time.Time       `json:"created_by_second" validate:"required"`
 	L1                 L1Net           `json:"l1" validate:"required"`
 	BeneficiaryAddress string          `json:"beneficiary_address"`
 	Step               int             `json:"step"`
 	Status             string          `json:"status"`
+	Error              string          `json:"error,omitempty"`
 	L2FundWallets      []L2FundWallets `json:"l2_wallets,omitempty"`
-}
-
-func NewRollupFromFile(file string) (*Rollup, error) {
-	cfg := &Rollup{}
-
-	if file := file; file != "" {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load config file, %w", err)
-		}
-		if err := json.Unmarshal(content, cfg); err != nil {
-			return nil, fmt.Errorf("failed to parse config file, %w", err)
-		}
-	}
-	return cfg, nil
+	BasePort           int             `json:"base_port"`
+	Node               NodeProfile     `json:"node"`
+	Consensus          ConsensusSpec   `json:"consensus"`
+	// Replicas are execution nodes behind the load balanced RpcUrl. The
+	// sequencer stays reachable at SequencerRpcUrl.
+	Replicas        int    `json:"replicas"`
+	SequencerRpcUrl string `json:"sequencer_rpc_url"`
+	// JwtRotatedAt is when the engine api secret was last rotated, zero when
+	// it was never rotated.
+	JwtRotatedAt time.Time `json:"jwt_rotated_at,omitempty"`
+	// ContractsVersion is the deployed version of the l1 and l2 rollup
+	// contracts, Upgrades the history of upgrade attempts.
+	ContractsVersion int               `json:"contracts_version"`
+	Upgrades         []ContractUpgrade `json:"upgrades,omitempty"`
+	// ForkedFrom is set on rollups whose genesis holds the state of another
+	// rollup.
+	ForkedFrom *RollupFork `json:"forked_from,omitempty"`
+	// FaucetAddress is the account of the faucet, premined in the genesis.
+	// It is empty when the rollup was created without a faucet.
+	Faucet        FaucetSpec `json:"faucet"`
+	FaucetAddress string     `json:"faucet_address,omitempty"`
+	// ExplorerUrl is the public url of the explorer, empty when the rollup
+	// runs none.
+	Explorer    ExplorerSpec `json:"explorer"`
+	ExplorerUrl string       `json:"explorer_url,omitempty"`
+	// Icon and NativeCurrency describe the chain to wallets, see chain.json.
+	Icon           string         `json:"icon,omitempty"`
+	NativeCurrency NativeCurrency `json:"native_currency"`
+	// Verification is the result of the latest end to end verification.
+	Verification *RollupVerification `json:"verification,omitempty"`
 }
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type L1Proxies struct {
 	AddressManager    string `json:"AddressManager"`
 	L1Rollup          string `json:"L1Rollup"`
@@ -21,3 +26,77 @@ type RollupContracts struct {
 	L1Proxies L1Proxies `json:"L1Proxies"`
 	L2Proxies L2Proxies `json:"L2Proxies"`
 }
+
+// ContractLogic is an implementation contract recorded by the contracts
+// repo. CodeHash is the keccak256 of the hex string of its deployed bytecode
+// as in the artifact, with the libraries still unlinked.
+type ContractLogic struct {
+	L1ChainId    int    `json:"L1ChainId"`
+	L2ChainId    int    `json:"L2ChainId,omitempty"`
+	CodeHash     string `json:"CodeHash"`
+	Address      string `json:"Address"`
+	ContractName string `json:"ContractName"`
+}
+
+// L1VersionedLogics are the l1 implementations and libraries of a version.
+type L1VersionedLogics struct {
+	L1ChainId          int           `json:"L1ChainId"`
+	Version            int           `json:"Version"`
+	ReceiptLibrary     ContractLogic `json:"ReceiptLibrary"`
+	TransactionLibrary ContractLogic `json:"TransactionLibrary"`
+	LibPropose         ContractLogic `json:"LibPropose"`
+	LibProve           ContractLogic `json:"LibProve"`
+	LibOnChain         ContractLogic `json:"LibOnChain"`
+	AddressManager     ContractLogic `json:"AddressManager"`
+	L1Rollup           ContractLogic `json:"L1Rollup"`
+	CrossChainChannel  ContractLogic `json:"CrossChainChannel"`
+	L1Escrow           ContractLogic `json:"L1Escrow"`
+}
+
+// L2VersionedLogics are the l2 implementations of a version, part of the
+// genesis of the rollup.
+type L2VersionedLogics struct {
+	L1ChainId         int           `json:"L1ChainId"`
+	L2ChainId         int           `json:"L2ChainId"`
+	Version           int           `json:"Version"`
+	AddressManager    ContractLogic `json:"AddressManager"`
+	L2Rollup          ContractLogic `json:"L2Rollup"`
+	CrossChainChannel ContractLogic `json:"CrossChainChannel"`
+	L2Escrow          ContractLogic `json:"L2Escrow"`
+}
+
+// Results of comparing the code of a contract on chain with its recorded
+// code hash.
+const (
+	CodeMatch     = "match"
+	CodeMismatch  = "mismatch"
+	CodeUnchecked = "unchecked"
+)
+
+// RollupContract is a contract of a rollup. Proxied contracts are served at
+// Address and run the code of Implementation, the others have no
+// Implementation.
+type RollupContract struct {
+	Layer          string `json:"layer"`
+	Name           string `json:"name"`
+	Address        string `json:"address"`
+	Implementation string `json:"implementation,omitempty"`
+	// OnChainImplementation is what the proxy points at, it differs from
+	// Implementation when the proxy was upgraded outside of g1g2.
+	OnChainImplementation string          `json:"onchain_implementation,omitempty"`
+	Version               int             `json:"version"`
+	CodeHash              string          `json:"code_hash,omitempty"`
+	Code                  string          `json:"code"`
+	CodeDetail            string          `json:"code_detail,omitempty"`
+	Abi                   json.RawMessage `json:"abi,omitempty"`
+}
+
+// RollupContractRegistry lists the l1 and l2 contracts of a rollup.
+type RollupContractRegistry struct {
+	Name      string           `json:"name"`
+	L1ChainId int              `json:"l1_chain_id"`
+	L2ChainId int              `json:"l2_chain_id"`
+	Version   int              `json:"version"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Contracts []RollupContract `json:"contracts"`
+}
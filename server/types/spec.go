@@ -0,0 +1,74 @@
+package types
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+type ImageSpec struct {
+	Execution string `yaml:"execution" json:"execution,omitempty"`
+	Consensus string `yaml:"consensus" json:"consensus,omitempty"`
+	// Prover is only used by rollups with a dedicated real prover.
+	Prover string `yaml:"prover" json:"prover,omitempty"`
+}
+
+// PortSpec holds the host ports of the l2 node. The http port is Base, ws is
+// Base+1 and the engine api is Base+2.
+type PortSpec struct {
+	Base int `yaml:"base" json:"base,omitempty"`
+}
+
+// RollupSpec is the declarative description of a rollup. Empty fields fall
+// back to the defaults of the server config.
+type RollupSpec struct {
+	Name               string          `yaml:"name" json:"name" validate:"required"`
+	ChainId            int             `yaml:"chain_id" json:"chain_id" validate:"required"`
+	L1                 L1Net           `yaml:"l1" json:"l1"`
+	BeneficiaryAddress string          `yaml:"beneficiary" json:"beneficiary"`
+	L2FundWallets      []L2FundWallets `yaml:"l2_wallets" json:"l2_wallets,omitempty"`
+	Images             ImageSpec       `yaml:"images" json:"images"`
+	Ports              PortSpec        `yaml:"ports" json:"ports"`
+	Consensus          ConsensusSpec   `yaml:"consensus" json:"consensus"`
+	Node               NodeProfile     `yaml:"node" json:"node"`
+	Replicas           int             `yaml:"replicas" json:"replicas,omitempty"`
+	Faucet             FaucetSpec      `yaml:"faucet" json:"faucet"`
+	Explorer           ExplorerSpec    `yaml:"explorer" json:"explorer"`
+	Icon               string          `yaml:"icon" json:"icon,omitempty"`
+	NativeCurrency     NativeCurrency  `yaml:"native_currency" json:"native_currency"`
+}
+
+const MaxReplicas = 16
+
+// NewRollupSpecFromFile reads a YAML or JSON rollup spec.
+func NewRollupSpecFromFile(file string) (*RollupSpec, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec file, %w", err)
+	}
+	spec := &RollupSpec{}
+	if err := yaml.UnmarshalStrict(content, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec file, %w", err)
+	}
+	if spec.Name == "" || spec.ChainId == 0 {
+		return nil, fmt.Errorf("spec file %s must set name and chain_id", file)
+	}
+	return spec, nil
+}
+
+func (r *CreateRollupRequest) Spec() *RollupSpec {
+	return &RollupSpec{
+		Name:               r.Name,
+		ChainId:            r.ChainId,
+		BeneficiaryAddress: r.BeneficiaryAddress,
+		L2FundWallets:      r.L2FundWallets,
+		Node:               r.Node,
+		Consensus:          r.Consensus,
+		Replicas:           r.Replicas,
+		Faucet:             r.Faucet,
+		Explorer:           r.Explorer,
+		Icon:               r.Icon,
+		NativeCurrency:     r.NativeCurrency,
+	}
+}
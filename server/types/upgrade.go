@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+type UpgradeContractsRequest struct {
+	Version int `json:"version" validate:"required"`
+}
+
+// ContractUpgrade records one attempt to upgrade the rollup contracts. Error
+// is set when the attempt failed and the rollup stayed at From.
+type ContractUpgrade struct {
+	From  int       `json:"from"`
+	To    int       `json:"to"`
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
+
+const (
+	ImageExecution = "execution"
+	ImageConsensus = "consensus"
+)
+
+type UpgradeImageRequest struct {
+	Image   string `json:"image" validate:"required,oneof=execution consensus"`
+	Version string `json:"version" validate:"required"`
+}
@@ -0,0 +1,49 @@
+package types
+
+import "time"
+
+// checks of a verification
+const (
+	VerifyBlocks      = "blocks"
+	VerifyTransaction = "transaction"
+	VerifyProposal    = "proposal"
+	VerifyProof       = "proof"
+	VerifyBridge      = "bridge"
+)
+
+// results of a check
+const (
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// VerifyCheck is the result of one check. Skipped checks do not apply to
+// the rollup, e.g. the proof check of a rollup without the prove role.
+type VerifyCheck struct {
+	Name     string        `json:"name"`
+	Result   string        `json:"result"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// RollupVerification checks end to end that a rollup works: blocks are
+// produced, transactions are included, blocks are proposed and proven on l1
+// and deposits cross the bridge.
+type RollupVerification struct {
+	Name      string        `json:"name"`
+	Passed    bool          `json:"passed"`
+	StartedAt time.Time     `json:"started_at"`
+	Checks    []VerifyCheck `json:"checks"`
+}
+
+// Failed lists the names of the failed checks.
+func (v *RollupVerification) Failed() []string {
+	failed := []string{}
+	for _, check := range v.Checks {
+		if check.Result == CheckFailed {
+			failed = append(failed, check.Name)
+		}
+	}
+	return failed
+}
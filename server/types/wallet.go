@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+// WalletTransfer is an l2 transfer from the rollup admin funding a wallet.
+type WalletTransfer struct {
+	Address   string    `json:"address"`
+	AmountWei string    `json:"amount_wei"`
+	TxHash    string    `json:"tx_hash"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// FundWalletsRequest adds or tops up wallets of a running rollup, sending
+// each the amount it lists.
+type FundWalletsRequest struct {
+	Wallets []L2FundWallets `json:"wallets" validate:"required,min=1"`
+}
+
+// WalletBalance is a funded wallet with its balance on l2.
+type WalletBalance struct {
+	L2FundWallets
+	BalanceWei string `json:"balance_wei"`
+}
+
+// GenesisWallets returns the wallets allocated in the genesis, without the
+// transfers that funded them later.
+func GenesisWallets(wallets []L2FundWallets) []L2FundWallets {
+	genesis := []L2FundWallets{}
+	for _, w := range wallets {
+		if w.AmountInWei != "" {
+			genesis = append(genesis, L2FundWallets{WalletAddress: w.WalletAddress, AmountInWei: w.AmountInWei})
+		}
+	}
+	return genesis
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ReceiptPollInterval is how often WaitReceipt asks for a receipt.
+const ReceiptPollInterval = 500 * time.Millisecond
+
+// SendTransfer signs a legacy transfer of amount from key with nonce at the
+// suggested gas price and sends it.
+func SendTransfer(
+	ctx context.Context,
+	client *ethclient.Client,
+	key *ecdsa.PrivateKey,
+	nonce uint64,
+	to common.Address,
+	amount *big.Int,
+) (*types.Transaction, error) {
+	chainId, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      21000,
+		To:       &to,
+		Value:    amount,
+	}), types.LatestSignerForChainID(chainId), key)
+	if err != nil {
+		return nil, err
+	}
+	return tx, client.SendTransaction(ctx, tx)
+}
+
+// WaitReceipt polls the receipt of transaction hash until it is included. It
+// fails when ctx is done first or when the transaction reverted, in which
+// case the receipt is returned too.
+func WaitReceipt(ctx context.Context, client *ethclient.Client, hash common.Hash) (*types.Receipt, error) {
+	for {
+		receipt, err := client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return receipt, fmt.Errorf("transaction %s reverted", hash)
+			}
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction %s is not included, %w", hash, ctx.Err())
+		case <-time.After(ReceiptPollInterval):
+		}
+	}
+}
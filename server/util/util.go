@@ -2,15 +2,20 @@ package util
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"bitbucket.org/creachadair/shell"
 	"github.com/bitfield/script"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -81,6 +86,30 @@ func ExecWrapper(cmd string) *script.Pipe {
 	return script.Exec(cmd)
 }
 
+// ExecIn runs cmd like ExecWrapper with dir as its working directory. The
+// working directory of the process is shared by every request, commands must
+// not change it.
+func ExecIn(dir, cmd string) *script.Pipe {
+	fmt.Print("🌟 " + cmd + " INNNNNN: " + dir + " 🌟")
+	println()
+	return script.NewPipe().Filter(func(r io.Reader, w io.Writer) error {
+		args, ok := shell.Split(cmd)
+		if !ok {
+			return fmt.Errorf("unbalanced quotes or backslashes in [%s]", cmd)
+		}
+		c := exec.Command(args[0], args[1:]...)
+		c.Dir = dir
+		c.Stdin = r
+		c.Stdout = w
+		c.Stderr = w
+		if err := c.Start(); err != nil {
+			fmt.Fprintln(w, err)
+			return err
+		}
+		return c.Wait()
+	})
+}
+
 func FileFromBase(basePath *string, pathRelativeToBase string) string {
 	return filepath.Join(*basePath, pathRelativeToBase)
 }
@@ -125,6 +154,31 @@ func GenerateL2GenesisHash(genesisFilePath string) (string, error) {
 	return hash.String(), err
 }
 
+// HashDir returns the sha256 of every regular file under dir, keyed by the
+// path relative to dir. A missing dir yields an empty map.
+func HashDir(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	if !DirExists(dir) {
+		return hashes, nil
+	}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hashes[rel] = fmt.Sprintf("%x", sha256.Sum256(content))
+		return nil
+	})
+	return hashes, err
+}
+
 func WriteJSONTo(data interface{}, path string) error {
 	bytes, err := json.MarshalIndent(data, "", " ")
 	if err != nil {
@@ -145,6 +199,42 @@ func ReadJSONTo(v any, path string) error {
 	return json.Unmarshal(byteValue, v)
 }
 
+// EnsureNodeKey reads the hex p2p node key at path, generating it first when
+// missing, and returns the node id of the key for enode urls.
+func EnsureNodeKey(path string) (string, error) {
+	key, err := crypto.LoadECDSA(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err = crypto.GenerateKey()
+		if err != nil {
+			return "", err
+		}
+		err = crypto.SaveECDSA(path, key)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", crypto.FromECDSAPub(&key.PublicKey)[1:]), nil
+}
+
+// WriteJwtSecret writes a random 32 byte engine api secret as hex to path.
+// An existing file is truncated in place so bind mounts see the new secret.
+func WriteJwtSecret(path string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(hexutil.Encode(secret)), 0600)
+}
+
+// EnsureJwtSecret writes a jwt secret to path unless one exists.
+func EnsureJwtSecret(path string) error {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return WriteJwtSecret(path)
+	}
+	return err
+}
+
 func CreateAccount() (string, string) {
 	// Generate a new private key
 	privateKey, err := crypto.GenerateKey()